@@ -3,173 +3,120 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/exec"
 	"strings"
 	"time"
 
+	"eaws/internal/state"
 	"eaws/internal/utils"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
-	"github.com/manifoldco/promptui"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/spf13/cobra"
 )
 
+var (
+	connectTarget  ecsTarget
+	connectCommand string
+	connectShell   string
+	connectLast    bool
+)
+
 // containerConnectCmd represents the container connect command
 var containerConnectCmd = &cobra.Command{
-	Use:     "connect",
+	Use:     "connect [-- command [args...]]",
 	Aliases: []string{"c"},
 	Short:   "Connect to some container",
-	Long:    `Connect to an ECS container using AWS Systems Manager Session Manager.`,
+	Long: `Connect to an ECS container using AWS Systems Manager Session Manager.
+
+Each level (cluster, service, task, container) is prompted for unless given with a flag.
+By default an interactive shell is opened, preferring bash and falling back to sh.
+
+A command always runs in a shell inside the container (sh, or the one given with --shell),
+on EC2 and Fargate alike. --command is a shell command line, so pipes and variables work and
+are expanded in the container. Arguments after '--' are quoted and passed as they are.
+
+Connections are remembered per account: recent clusters, services and containers are listed
+first, and --last reconnects to the last one, using the newest running task if the old task
+is gone.
+
+Examples:
+  eaws container connect --cluster prod --service api
+  eaws c c --cluster prod --service api --task 0 --container app
+  eaws c c --service api -- rails console
+  eaws c c --service db -- psql -c "select 1"
+  eaws c c --service api --command "bin/rails db:migrate"
+  eaws c c --service api --shell ash
+  eaws c c --last`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		startTime := time.Now()
 
-		if err := utils.CheckAWSProfile(profile); err != nil {
-			return fmt.Errorf("failed to configure AWS profile: %w", err)
-		}
-		if verbose {
-			utils.PrintInfo(fmt.Sprintf("✓ AWS profile check: %v", time.Since(startTime)))
-		}
-
-		cfg, err := utils.LoadAWSConfig(profile)
+		command, err := remoteCommand(cmd, args)
 		if err != nil {
-			return fmt.Errorf("failed to load AWS config: %w", err)
+			return err
 		}
 
-		ecsClient := ecs.NewFromConfig(cfg)
-		ctx := context.Background()
-
-		// Get clusters
-		stepStart := time.Now()
-		clustersOutput, err := ecsClient.ListClusters(ctx, &ecs.ListClustersInput{})
-		if err != nil {
-			return fmt.Errorf("failed to list clusters: %w", err)
-		}
-		if verbose {
-			utils.PrintInfo(fmt.Sprintf("✓ List clusters: %v", time.Since(stepStart)))
+		// Fail before picking a target rather than after starting a session
+		if _, err := utils.FindSessionManagerPlugin(); err != nil {
+			return err
 		}
 
-		if len(clustersOutput.ClusterArns) == 0 {
-			utils.PrintWarning("No clusters found")
-			return nil
-		}
+		cfg := awsConfig(cmd)
+		loadRecentTargets(cmd)
 
-		// Extract cluster names
-		var clusterNames []string
-		for _, clusterArn := range clustersOutput.ClusterArns {
-			parts := strings.Split(clusterArn, "/")
-			if len(parts) > 1 {
-				clusterNames = append(clusterNames, parts[len(parts)-1])
+		target := connectTarget
+		var last state.Target
+		if connectLast {
+			if target != (ecsTarget{}) {
+				return fmt.Errorf("use either --last or --cluster, --service, --task and --container, not both")
 			}
-		}
-
-		// Interactive cluster selection
-		clusterPrompt := promptui.Select{
-			Label: "Select cluster",
-			Items: clusterNames,
-		}
-
-		_, selectedCluster, err := clusterPrompt.Run()
-		if err != nil {
-			return fmt.Errorf("cluster selection cancelled: %w", err)
-		}
 
-		utils.PrintInfo(fmt.Sprintf("Selected cluster: %s", utils.GreenBold(selectedCluster)))
-
-		// Get services in the selected cluster
-		stepStart = time.Now()
-		servicesOutput, err := ecsClient.ListServices(ctx, &ecs.ListServicesInput{
-			Cluster: &selectedCluster,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to list services: %w", err)
-		}
-		if verbose {
-			utils.PrintInfo(fmt.Sprintf("✓ List services: %v", time.Since(stepStart)))
-		}
-
-		if len(servicesOutput.ServiceArns) == 0 {
-			utils.PrintWarning("No services found in this cluster")
-			return nil
-		}
-
-		// Extract service names
-		var serviceNames []string
-		for _, serviceArn := range servicesOutput.ServiceArns {
-			parts := strings.Split(serviceArn, "/")
-			if len(parts) > 2 {
-				serviceNames = append(serviceNames, parts[len(parts)-1])
+			// --region limits --last to it; otherwise the last target's region is used, whatever
+			// region the profile or context defaults to
+			lastRegion := ""
+			if cmd.Flags().Changed("region") {
+				lastRegion = cfg.Region
+			}
+			var found bool
+			if last, found = lastTarget(lastRegion); !found {
+				return fmt.Errorf("no previous connection in account %s. Connect once without --last", callerIdentity(cmd).Account())
+			}
+			if last.Region != cfg.Region {
+				cfg = cfg.Copy()
+				cfg.Region = last.Region
 			}
-		}
-
-		// Interactive service selection
-		servicePrompt := promptui.Select{
-			Label: "Select service",
-			Items: serviceNames,
-		}
 
-		_, selectedService, err := servicePrompt.Run()
-		if err != nil {
-			return fmt.Errorf("service selection cancelled: %w", err)
+			utils.PrintInfo(fmt.Sprintf("Reconnecting to %s", utils.GreenBold(describeTarget(last))))
+			target = ecsTarget{Cluster: last.Cluster, Service: last.Service, Container: last.Container}
 		}
 
-		utils.PrintInfo(fmt.Sprintf("Selected service: %s", utils.GreenBold(selectedService)))
+		ecsClient := ecs.NewFromConfig(cfg)
+		ctx := cmd.Context()
 
-		// Get tasks for the selected service
-		stepStart = time.Now()
-		tasksOutput, err := ecsClient.ListTasks(ctx, &ecs.ListTasksInput{
-			Cluster:     &selectedCluster,
-			ServiceName: &selectedService,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to list tasks: %w", err)
-		}
-		if verbose {
-			utils.PrintInfo(fmt.Sprintf("✓ List tasks: %v", time.Since(stepStart)))
+		selectedCluster, err := selectCluster(ctx, ecsClient, target.Cluster, nil)
+		if err != nil || selectedCluster == "" {
+			return err
 		}
 
-		if len(tasksOutput.TaskArns) == 0 {
-			utils.PrintWarning("No tasks found for this service")
-			return nil
+		selectedService, err := selectService(ctx, ecsClient, selectedCluster, target.Service)
+		if err != nil || selectedService == "" {
+			return err
 		}
 
-		var selectedTask string
-
-		// If only one task, use it directly
-		if len(tasksOutput.TaskArns) == 1 {
-			selectedTask = tasksOutput.TaskArns[0]
-			utils.PrintInfo(fmt.Sprintf("Using task: %s", utils.GreenBold(selectedTask)))
-		} else {
-			// Multiple tasks, let user choose
-			var taskNames []string
-			for _, taskArn := range tasksOutput.TaskArns {
-				// Extract task ID from ARN for display
-				parts := strings.Split(taskArn, "/")
-				if len(parts) > 1 {
-					taskNames = append(taskNames, parts[len(parts)-1])
-				} else {
-					taskNames = append(taskNames, taskArn)
-				}
-			}
-
-			// Interactive task selection
-			taskPrompt := promptui.Select{
-				Label: "Select task",
-				Items: taskNames,
-			}
-
-			taskIndex, _, err := taskPrompt.Run()
-			if err != nil {
-				return fmt.Errorf("task selection cancelled: %w", err)
+		if connectLast {
+			if target.Task, err = freshTask(ctx, ecsClient, selectedCluster, selectedService, last.Task); err != nil {
+				return err
 			}
+		}
 
-			selectedTask = tasksOutput.TaskArns[taskIndex]
-			utils.PrintInfo(fmt.Sprintf("Selected task: %s", utils.GreenBold(selectedTask)))
+		selectedTask, err := selectTask(ctx, ecsClient, selectedCluster, selectedService, target.Task)
+		if err != nil || selectedTask == "" {
+			return err
 		}
 
 		// Describe the task to get container details
-		stepStart = time.Now()
+		stepStart := time.Now()
 		describeOutput, err := ecsClient.DescribeTasks(ctx, &ecs.DescribeTasksInput{
 			Cluster: &selectedCluster,
 			Tasks:   []string{selectedTask},
@@ -187,67 +134,46 @@ var containerConnectCmd = &cobra.Command{
 
 		task := describeOutput.Tasks[0]
 
-		// Get container instance ID
-		containerInstanceArn := task.ContainerInstanceArn
-		if containerInstanceArn == nil {
-			return fmt.Errorf("no container instance found for task")
+		selectedContainer, err := selectContainer(task, target.Container)
+		if err != nil {
+			return err
 		}
 
-		// Get container runtime ID
-		if len(task.Containers) == 0 {
-			return fmt.Errorf("no containers found in task")
+		if selectedContainer.RuntimeId == nil {
+			return fmt.Errorf("no runtime ID found for container")
 		}
 
-		var selectedContainer *types.Container
-
-		// If only one container, use it directly
-		if len(task.Containers) == 1 {
-			selectedContainer = &task.Containers[0]
-			utils.PrintInfo(fmt.Sprintf("Using container: %s", utils.GreenBold(*selectedContainer.Name)))
-		} else {
-			// Multiple containers, let user choose
-			var containerNames []string
-			for _, container := range task.Containers {
-				if container.Name != nil {
-					containerNames = append(containerNames, *container.Name)
-				}
-			}
-
-			// Interactive container selection
-			containerPrompt := promptui.Select{
-				Label: "Select container",
-				Items: containerNames,
-			}
+		if err := confirmProtected(cmd, fmt.Sprintf("open a session in container %s", *selectedContainer.Name), selectedCluster); err != nil {
+			return err
+		}
 
-			_, selectedContainerName, err := containerPrompt.Run()
-			if err != nil {
-				return fmt.Errorf("container selection cancelled: %w", err)
-			}
+		rememberTarget(cmd, state.Target{
+			Region:    cfg.Region,
+			Cluster:   selectedCluster,
+			Service:   selectedService,
+			Task:      resourceName(selectedTask),
+			Container: *selectedContainer.Name,
+		})
 
-			// Find the selected container
-			for _, container := range task.Containers {
-				if container.Name != nil && *container.Name == selectedContainerName {
-					selectedContainer = &container
-					break
-				}
+		// Fargate tasks have no container instance to SSM into, so go through ECS Exec
+		if usesECSExec(task) {
+			stepStart = time.Now()
+			if err := executeCommand(ctx, cfg, ecsClient, selectedCluster, task, selectedContainer, command); err != nil {
+				return err
 			}
 
-			if selectedContainer == nil {
-				return fmt.Errorf("selected container not found")
+			if verbose {
+				utils.PrintInfo(fmt.Sprintf("✓ ECS Exec session completed: %v", time.Since(stepStart)))
+				utils.PrintInfo(fmt.Sprintf("✓ Total execution time: %v", time.Since(startTime)))
 			}
-
-			utils.PrintInfo(fmt.Sprintf("Selected container: %s", utils.GreenBold(*selectedContainer.Name)))
-		}
-
-		if selectedContainer.RuntimeId == nil {
-			return fmt.Errorf("no runtime ID found for container")
+			return nil
 		}
 
 		// Get EC2 instance ID
 		stepStart = time.Now()
 		containerInstanceOutput, err := ecsClient.DescribeContainerInstances(ctx, &ecs.DescribeContainerInstancesInput{
 			Cluster:            &selectedCluster,
-			ContainerInstances: []string{*containerInstanceArn},
+			ContainerInstances: []string{*task.ContainerInstanceArn},
 		})
 		if err != nil {
 			return fmt.Errorf("failed to describe container instance: %w", err)
@@ -268,25 +194,14 @@ var containerConnectCmd = &cobra.Command{
 		utils.PrintInfo(fmt.Sprintf("EC2 Instance: %s", utils.GreenBold(*ec2InstanceId)))
 
 		// Start SSM session
-		documentName := "AWS-StartInteractiveCommand"
-		command := fmt.Sprintf("sudo docker exec -ti %s sh", *selectedContainer.RuntimeId)
+		dockerCommand := fmt.Sprintf("sudo docker exec -ti %s %s", *selectedContainer.RuntimeId, command)
 
-		utils.PrintInfo(fmt.Sprintf("Starting session with command: %s", utils.Cyan(command)))
+		utils.PrintInfo(fmt.Sprintf("Starting session with command: %s", utils.Cyan(dockerCommand)))
 		utils.PrintInfo("🚀 Connecting to container...")
 
-		// Use AWS CLI to start the session since SSM client doesn't support interactive sessions directly
 		stepStart = time.Now()
-		ssmCmd := exec.Command("aws", "ssm", "start-session",
-			"--target", *ec2InstanceId,
-			"--document-name", documentName,
-			"--parameters", fmt.Sprintf("command=%s", command))
-
-		ssmCmd.Stdin = os.Stdin
-		ssmCmd.Stdout = os.Stdout
-		ssmCmd.Stderr = os.Stderr
-
-		if err := ssmCmd.Run(); err != nil {
-			return fmt.Errorf("failed to start SSM session: %w", err)
+		if err := startSSMSession(ctx, cfg, *ec2InstanceId, dockerCommand); err != nil {
+			return err
 		}
 
 		if verbose {
@@ -297,6 +212,133 @@ var containerConnectCmd = &cobra.Command{
 	},
 }
 
+// autoShellCommand starts bash when the image ships it and falls back to sh otherwise
+const autoShellCommand = `sh -c 'if command -v bash >/dev/null 2>&1; then exec bash; else exec sh; fi'`
+
+// remoteCommand builds the command to run in the container from --command, the
+// arguments after "--" and --shell
+func remoteCommand(cmd *cobra.Command, args []string) (string, error) {
+	if len(args) > 0 && cmd.ArgsLenAtDash() != 0 {
+		return "", fmt.Errorf("unexpected arguments %q. Pass the command to run after '--', e.g. eaws c c -- rails console", args)
+	}
+
+	command := connectCommand
+	if len(args) > 0 {
+		if command != "" {
+			return "", fmt.Errorf("use either --command or arguments after '--', not both")
+		}
+		// Keep the user's quoting: every argument reaches the program unchanged
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = shellQuote(arg)
+		}
+		command = strings.Join(quoted, " ")
+	}
+
+	if command == "" {
+		if connectShell == "auto" {
+			return autoShellCommand, nil
+		}
+		return connectShell, nil
+	}
+
+	// A shell in the container interprets the command, which allows pipes and variables.
+	// Quoting it keeps the EC2 host's shell from interpreting it first.
+	shell := "sh"
+	if connectShell != "auto" {
+		shell = connectShell
+	}
+	return fmt.Sprintf("%s -c %s", shell, shellQuote(command)), nil
+}
+
+// shellQuote wraps s in single quotes for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// usesECSExec reports whether the task must be reached through ECS Exec instead of docker exec on its EC2 host
+func usesECSExec(task types.Task) bool {
+	return task.LaunchType != types.LaunchTypeEc2 || task.ContainerInstanceArn == nil
+}
+
+// executeCommand runs command interactively in the container through ECS Exec
+func executeCommand(ctx context.Context, cfg aws.Config, client *ecs.Client, cluster string, task types.Task, container *types.Container, command string) error {
+	if !task.EnableExecuteCommand {
+		return fmt.Errorf("ECS Exec is not enabled for this task. Enable it on the service with 'aws ecs update-service --cluster %s --service <service> --enable-execute-command --force-new-deployment'", cluster)
+	}
+
+	taskArn := *task.TaskArn
+
+	utils.PrintInfo(fmt.Sprintf("Starting ECS Exec session with command: %s", utils.Cyan(command)))
+	utils.PrintInfo("🚀 Connecting to container...")
+
+	output, err := client.ExecuteCommand(ctx, &ecs.ExecuteCommandInput{
+		Cluster:     &cluster,
+		Task:        &taskArn,
+		Container:   container.Name,
+		Command:     &command,
+		Interactive: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute command: %w", err)
+	}
+
+	if output.Session == nil {
+		return fmt.Errorf("no session returned by ECS Exec")
+	}
+
+	session := utils.SessionManagerSession{
+		SessionId:  aws.ToString(output.Session.SessionId),
+		StreamUrl:  aws.ToString(output.Session.StreamUrl),
+		TokenValue: aws.ToString(output.Session.TokenValue),
+	}
+
+	// ECS Exec targets have the form ecs:<cluster>_<task id>_<container runtime id>
+	target := fmt.Sprintf("ecs:%s_%s_%s", cluster, resourceName(taskArn), *container.RuntimeId)
+
+	if err := utils.RunSessionManagerPlugin(ctx, cfg, session, map[string]string{"Target": target}); err != nil {
+		return fmt.Errorf("failed to start ECS Exec session: %w", err)
+	}
+
+	return nil
+}
+
+// startSSMSession runs command on an EC2 instance through Session Manager
+func startSSMSession(ctx context.Context, cfg aws.Config, instanceID, command string) error {
+	input := &ssm.StartSessionInput{
+		Target:       aws.String(instanceID),
+		DocumentName: aws.String("AWS-StartInteractiveCommand"),
+		Parameters:   map[string][]string{"command": {command}},
+	}
+
+	output, err := ssm.NewFromConfig(cfg).StartSession(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to start SSM session: %w", err)
+	}
+
+	session := utils.SessionManagerSession{
+		SessionId:  aws.ToString(output.SessionId),
+		StreamUrl:  aws.ToString(output.StreamUrl),
+		TokenValue: aws.ToString(output.TokenValue),
+	}
+
+	// The plugin gets the start request in the form the AWS CLI passes it
+	request := map[string]any{
+		"Target":       instanceID,
+		"DocumentName": aws.ToString(input.DocumentName),
+		"Parameters":   input.Parameters,
+	}
+
+	if err := utils.RunSessionManagerPlugin(ctx, cfg, session, request); err != nil {
+		return fmt.Errorf("failed to start SSM session: %w", err)
+	}
+	return nil
+}
+
 func init() {
 	containerCmd.AddCommand(containerConnectCmd)
+	connectTarget.addFlags(containerConnectCmd)
+	containerConnectCmd.Flags().StringVar(&connectCommand, "command", "", "Command to run instead of an interactive shell")
+	containerConnectCmd.Flags().StringVar(&connectShell, "shell", "auto", "Shell to start (auto probes for bash, then sh)")
+	containerConnectCmd.Flags().BoolVar(&connectLast, "last", false, "Reconnect to the container connected to last in this account")
 }
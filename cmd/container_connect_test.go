@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRemoteCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		argv    []string
+		command string
+		shell   string
+		want    string
+		wantErr bool
+	}{
+		{name: "auto shell", shell: "auto", want: autoShellCommand},
+		{name: "shell without a command", shell: "ash", want: "ash"},
+		{
+			name:  "arguments after --",
+			argv:  []string{"--", "rails", "console"},
+			shell: "auto",
+			want:  `sh -c ''\''rails'\'' '\''console'\'''`,
+		},
+		{
+			name:  "arguments with spaces and quotes",
+			argv:  []string{"--", "psql", "-c", "select 'a b'"},
+			shell: "auto",
+			want:  `sh -c ''\''psql'\'' '\''-c'\'' '\''select '\''\'\'''\''a b'\''\'\'''\'''\'''`,
+		},
+		{
+			name:    "command with a pipe",
+			command: "ps aux | grep ruby",
+			shell:   "auto",
+			want:    `sh -c 'ps aux | grep ruby'`,
+		},
+		{
+			name:    "shell with a command",
+			command: "echo $HOME",
+			shell:   "bash",
+			want:    `bash -c 'echo $HOME'`,
+		},
+		{
+			name:    "command and arguments together",
+			argv:    []string{"--", "ls"},
+			command: "ps",
+			shell:   "auto",
+			wantErr: true,
+		},
+		{name: "arguments without --", argv: []string{"ls"}, shell: "auto", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			if err := cmd.Flags().Parse(tt.argv); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			oldCommand, oldShell := connectCommand, connectShell
+			defer func() { connectCommand, connectShell = oldCommand, oldShell }()
+			connectCommand, connectShell = tt.command, tt.shell
+
+			got, err := remoteCommand(cmd, cmd.Flags().Args())
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("remoteCommand() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("remoteCommand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("remoteCommand() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,94 +3,483 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
+	"eaws/internal/output"
 	"eaws/internal/utils"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
-	"github.com/manifoldco/promptui"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/spf13/cobra"
 )
 
+var (
+	listCluster     string
+	listAllRegions  bool
+	listTasks       bool
+	listAllClusters bool
+)
+
+// inventoryParallelism bounds how many clusters --all-clusters describes at once
+const inventoryParallelism = 8
+
 // containerListCmd represents the container list command
 var containerListCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"l"},
 	Short:   "List all containers in clusters",
-	Long:    `List all containers in ECS clusters with interactive selection.`,
+	Long: `List the services of an ECS cluster with their desired, running and pending task counts,
+launch type, task definition revision and deployment rollout state. With --tasks, every task
+is listed with its status and health. Services that are not settled are highlighted.
+
+With --all-clusters every cluster is described concurrently and listed together, answering
+what is running in the account; add --all-regions to cover every region.
+
+Examples:
+  eaws container list --cluster prod
+  eaws c l --cluster prod --tasks
+  eaws c l --all-clusters --all-regions -o json
+  eaws c l --cluster prod -o json | jq -r '.services[].name'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := utils.CheckAWSProfile(profile); err != nil {
-			return fmt.Errorf("failed to configure AWS profile: %w", err)
+		if listAllClusters && listCluster != "" {
+			return fmt.Errorf("use either --cluster or --all-clusters, not both")
 		}
 
-		cfg, err := utils.LoadAWSConfig(profile)
+		cfg := awsConfig(cmd)
+
+		ctx := cmd.Context()
+
+		regions, err := targetRegions(ctx, cfg, listAllRegions)
 		if err != nil {
-			return fmt.Errorf("failed to load AWS config: %w", err)
+			return err
 		}
 
-		client := ecs.NewFromConfig(cfg)
-		ctx := context.Background()
+		// Get clusters in every region
+		results := utils.InRegions(ctx, cfg, regions, func(ctx context.Context, regionCfg aws.Config) ([]string, error) {
+			return utils.ListClusterArns(ctx, ecs.NewFromConfig(regionCfg))
+		})
 
-		// Get clusters
-		clustersOutput, err := client.ListClusters(ctx, &ecs.ListClustersInput{})
-		if err != nil {
-			return fmt.Errorf("failed to list clusters: %w", err)
+		var clusters []regionalName
+		for _, result := range results {
+			if result.Err != nil {
+				if len(regions) == 1 {
+					return fmt.Errorf("failed to list clusters: %w", result.Err)
+				}
+				utils.PrintWarning(fmt.Sprintf("Skipping region %s: failed to list clusters: %v", result.Region, result.Err))
+				continue
+			}
+			for _, clusterArn := range result.Value {
+				// Only offer the clusters of the active context
+				if name := resourceName(clusterArn); activeContext.MatchCluster(name) {
+					clusters = append(clusters, regionalName{Region: result.Region, Name: name})
+				}
+			}
 		}
 
-		if len(clustersOutput.ClusterArns) == 0 {
+		if len(clusters) == 0 {
 			utils.PrintWarning("No clusters found")
 			return nil
 		}
 
-		// Extract cluster names
+		if listAllClusters {
+			return listInventory(ctx, cfg, clusters)
+		}
+
+		// Extract cluster names, labelled by region when several regions were visited
 		var clusterNames []string
-		for _, clusterArn := range clustersOutput.ClusterArns {
-			parts := strings.Split(clusterArn, "/")
-			if len(parts) > 1 {
-				clusterNames = append(clusterNames, parts[len(parts)-1])
-			}
+		for _, cluster := range clusters {
+			clusterNames = append(clusterNames, cluster.label(len(regions) > 1))
 		}
 
-		// Interactive cluster selection
-		prompt := promptui.Select{
-			Label: "Select cluster",
-			Items: clusterNames,
+		clusterValue := listCluster
+		if clusterValue == "" {
+			clusterValue = activeContext.Cluster
 		}
 
-		_, selectedCluster, err := prompt.Run()
-		if err != nil {
-			return fmt.Errorf("cluster selection cancelled: %w", err)
+		var details []string
+		if clusterValue == "" && !outputFormat.IsMachine() {
+			details = regionalClusterDetails(ctx, cfg, clusters)
 		}
 
-		utils.PrintInfo(fmt.Sprintf("Selected cluster: %s", utils.GreenBold(selectedCluster)))
+		clusterIndex, _, err := selectItem("Select cluster", "cluster", clusterNames, details, clusterValue)
+		if err != nil {
+			return err
+		}
 
-		// Get services in the selected cluster
-		servicesOutput, err := client.ListServices(ctx, &ecs.ListServicesInput{
-			Cluster: &selectedCluster,
+		selectedCluster := clusters[clusterIndex]
+		client := ecs.NewFromConfig(cfg, func(o *ecs.Options) {
+			o.Region = selectedCluster.Region
 		})
+
+		utils.PrintInfo(fmt.Sprintf("Selected cluster: %s", utils.GreenBold(clusterNames[clusterIndex])))
+
+		result, err := describeClusterServices(ctx, client, selectedCluster, listTasks)
 		if err != nil {
-			return fmt.Errorf("failed to list services: %w", err)
+			return err
 		}
 
-		if len(servicesOutput.ServiceArns) == 0 {
-			utils.PrintWarning("No services found in this cluster")
-			return nil
+		return printClusterServices(result)
+	},
+}
+
+// regionalClusterDetails describes clusters of several regions for the selector
+func regionalClusterDetails(ctx context.Context, cfg aws.Config, clusters []regionalName) []string {
+	namesByRegion := make(map[string][]string)
+	for _, cluster := range clusters {
+		namesByRegion[cluster.Region] = append(namesByRegion[cluster.Region], cluster.Name)
+	}
+
+	detailsByCluster := make(map[regionalName]string)
+	for clusterRegion, names := range namesByRegion {
+		client := ecs.NewFromConfig(cfg, func(o *ecs.Options) {
+			o.Region = clusterRegion
+		})
+		for i, detail := range clusterDetails(ctx, client, names) {
+			detailsByCluster[regionalName{Region: clusterRegion, Name: names[i]}] = detail
 		}
+	}
 
-		// Extract service names and print them
-		fmt.Printf("\n%s\n", utils.GreenBold("Services in cluster:"))
-		for _, serviceArn := range servicesOutput.ServiceArns {
-			parts := strings.Split(serviceArn, "/")
-			if len(parts) > 2 {
-				serviceName := parts[len(parts)-1]
-				fmt.Printf("  • %s\n", serviceName)
+	details := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		details[i] = detailsByCluster[cluster]
+	}
+	return details
+}
+
+// listInventory describes every cluster concurrently and prints them all together
+func listInventory(ctx context.Context, cfg aws.Config, clusters []regionalName) error {
+	results := utils.Parallel(ctx, clusters, inventoryParallelism, func(ctx context.Context, cluster regionalName) (clusterServices, error) {
+		client := ecs.NewFromConfig(cfg, func(o *ecs.Options) {
+			o.Region = cluster.Region
+		})
+		return describeClusterServices(ctx, client, cluster, listTasks)
+	})
+
+	inventory := []clusterServices{}
+	for _, result := range results {
+		if result.Err != nil {
+			if len(clusters) == 1 {
+				return result.Err
 			}
+			utils.PrintWarning(fmt.Sprintf("Skipping cluster %s: %v", result.Item.label(true), result.Err))
+			continue
+		}
+		inventory = append(inventory, result.Value)
+	}
+
+	return printInventory(inventory)
+}
+
+// clusterServices is the result of container list
+type clusterServices struct {
+	Cluster  string          `json:"cluster" yaml:"cluster"`
+	Region   string          `json:"region" yaml:"region"`
+	Services []serviceStatus `json:"services" yaml:"services"`
+	// Failures lists the services and tasks that were listed but couldn't be described
+	Failures []describeFailure `json:"failures,omitempty" yaml:"failures,omitempty"`
+}
+
+// describeFailure is a service or task ECS couldn't describe, e.g. because it is gone
+type describeFailure struct {
+	ARN    string `json:"arn" yaml:"arn"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// addFailures records failures of a describe call and warns about them
+func (c *clusterServices) addFailures(kind string, failures []types.Failure) {
+	for _, failure := range failures {
+		f := describeFailure{ARN: aws.ToString(failure.Arn), Reason: aws.ToString(failure.Reason)}
+		if detail := aws.ToString(failure.Detail); detail != "" {
+			f.Reason += ": " + detail
 		}
+		c.Failures = append(c.Failures, f)
+		utils.PrintWarning(fmt.Sprintf("Failed to describe %s %s in cluster %s: %s", kind, resourceName(f.ARN), c.Cluster, f.Reason))
+	}
+}
+
+// serviceStatus describes one ECS service
+type serviceStatus struct {
+	Name           string `json:"name" yaml:"name"`
+	Status         string `json:"status" yaml:"status"`
+	LaunchType     string `json:"launchType" yaml:"launchType"`
+	TaskDefinition string `json:"taskDefinition" yaml:"taskDefinition"`
+	Desired        int32  `json:"desired" yaml:"desired"`
+	Running        int32  `json:"running" yaml:"running"`
+	Pending        int32  `json:"pending" yaml:"pending"`
+	// Rollout is the rollout state of the primary deployment, empty for external deployment controllers
+	Rollout string       `json:"rollout,omitempty" yaml:"rollout,omitempty"`
+	Tasks   []taskStatus `json:"tasks,omitempty" yaml:"tasks,omitempty"`
+}
+
+// taskStatus describes one task of a service
+type taskStatus struct {
+	ID             string     `json:"id" yaml:"id"`
+	LastStatus     string     `json:"lastStatus" yaml:"lastStatus"`
+	DesiredStatus  string     `json:"desiredStatus" yaml:"desiredStatus"`
+	Health         string     `json:"health" yaml:"health"`
+	TaskDefinition string     `json:"taskDefinition" yaml:"taskDefinition"`
+	StartedAt      *time.Time `json:"startedAt,omitempty" yaml:"startedAt,omitempty"`
+}
+
+// settled reports whether the service runs what it should and no deployment is under way
+func (s serviceStatus) settled() bool {
+	return s.Running == s.Desired && s.Pending == 0 && (s.Rollout == "" || s.Rollout == string(types.DeploymentRolloutStateCompleted))
+}
+
+// describeClusterServices describes every service of a cluster and, with withTasks, their tasks
+func describeClusterServices(ctx context.Context, client *ecs.Client, cluster regionalName, withTasks bool) (clusterServices, error) {
+	result := clusterServices{
+		Cluster:  cluster.Name,
+		Region:   cluster.Region,
+		Services: []serviceStatus{},
+	}
+
+	serviceArns, err := utils.ListServiceArns(ctx, client, cluster.Name)
+	if err != nil {
+		return result, fmt.Errorf("failed to list services: %w", err)
+	}
 
+	services, failures, err := utils.DescribeServices(ctx, client, cluster.Name, serviceArns)
+	if err != nil {
+		return result, fmt.Errorf("failed to describe services: %w", err)
+	}
+	result.addFailures("service", failures)
+
+	for _, service := range services {
+		status := newServiceStatus(service)
+
+		if withTasks {
+			taskArns, err := utils.ListTaskArns(ctx, client, cluster.Name, status.Name)
+			if err != nil {
+				return result, fmt.Errorf("failed to list tasks of %s: %w", status.Name, err)
+			}
+			tasks, failures, err := utils.DescribeTasks(ctx, client, cluster.Name, taskArns)
+			if err != nil {
+				return result, fmt.Errorf("failed to describe tasks of %s: %w", status.Name, err)
+			}
+			result.addFailures("task", failures)
+			status.Tasks = []taskStatus{}
+			for _, task := range tasks {
+				status.Tasks = append(status.Tasks, newTaskStatus(task))
+			}
+		}
+
+		result.Services = append(result.Services, status)
+	}
+
+	sort.Slice(result.Services, func(i, j int) bool {
+		return result.Services[i].Name < result.Services[j].Name
+	})
+	return result, nil
+}
+
+// newServiceStatus converts a DescribeServices result
+func newServiceStatus(service types.Service) serviceStatus {
+	status := serviceStatus{
+		Name:           aws.ToString(service.ServiceName),
+		Status:         aws.ToString(service.Status),
+		LaunchType:     string(service.LaunchType),
+		TaskDefinition: resourceName(aws.ToString(service.TaskDefinition)),
+		Desired:        service.DesiredCount,
+		Running:        service.RunningCount,
+		Pending:        service.PendingCount,
+	}
+
+	// Services on capacity providers have no launch type
+	if status.LaunchType == "" {
+		var providers []string
+		for _, strategy := range service.CapacityProviderStrategy {
+			providers = append(providers, aws.ToString(strategy.CapacityProvider))
+		}
+		status.LaunchType = strings.Join(providers, ",")
+	}
+
+	for _, deployment := range service.Deployments {
+		if aws.ToString(deployment.Status) == "PRIMARY" {
+			status.Rollout = string(deployment.RolloutState)
+		}
+	}
+
+	return status
+}
+
+// summary describes the service in one line for selectors
+func (s serviceStatus) summary() string {
+	return joinDetails(s.Status, fmt.Sprintf("%d/%d running", s.Running, s.Desired), s.TaskDefinition, s.Rollout)
+}
+
+// newTaskStatus converts a DescribeTasks result
+func newTaskStatus(task types.Task) taskStatus {
+	return taskStatus{
+		ID:             resourceName(aws.ToString(task.TaskArn)),
+		LastStatus:     aws.ToString(task.LastStatus),
+		DesiredStatus:  aws.ToString(task.DesiredStatus),
+		Health:         string(task.HealthStatus),
+		TaskDefinition: resourceName(aws.ToString(task.TaskDefinitionArn)),
+		StartedAt:      task.StartedAt,
+	}
+}
+
+// summary describes the task in one line for selectors
+func (t taskStatus) summary() string {
+	age := ""
+	if t.StartedAt != nil {
+		age = "up " + formatAge(time.Since(*t.StartedAt))
+	}
+	return joinDetails(t.LastStatus, t.Health, t.TaskDefinition, age)
+}
+
+// printClusterServices prints the services of a cluster in the selected output format
+func printClusterServices(result clusterServices) error {
+	switch outputFormat {
+	case output.JSON, output.YAML:
+		return output.Write(os.Stdout, outputFormat, result)
+	case output.Table:
+		return renderServiceTables([]clusterServices{result})
+	}
+
+	if len(result.Services) == 0 {
+		utils.PrintWarning("No services found in this cluster")
 		return nil
-	},
+	}
+
+	printClusterTables(result)
+	return nil
+}
+
+// printInventory prints the services of several clusters in the selected output format,
+// grouped by cluster
+func printInventory(inventory []clusterServices) error {
+	switch outputFormat {
+	case output.JSON, output.YAML:
+		return output.Write(os.Stdout, outputFormat, inventory)
+	case output.Table:
+		return renderServiceTables(inventory)
+	}
+
+	for _, result := range inventory {
+		if len(result.Services) == 0 {
+			fmt.Printf("\n%s %s\n", utils.GreenBold("No services in cluster"), utils.GreenBold(regionalName{Region: result.Region, Name: result.Cluster}.label(true)))
+			continue
+		}
+		printClusterTables(result)
+	}
+	return nil
+}
+
+// printClusterTables prints the colorized service table of a cluster, and its task table with --tasks
+func printClusterTables(result clusterServices) {
+	fmt.Printf("\n%s %s\n\n", utils.GreenBold("Services in cluster"), utils.GreenBold(regionalName{Region: result.Region, Name: result.Cluster}.label(true)))
+	printServiceTable(result.Services)
+
+	if listTasks {
+		fmt.Printf("\n%s\n\n", utils.GreenBold("Tasks:"))
+		printTaskTable(result.Services)
+	}
+}
+
+// renderServiceTables prints the services of clusters as one plain table, and their tasks as
+// a second one with --tasks
+func renderServiceTables(results []clusterServices) error {
+	table := output.NewTable("REGION", "CLUSTER", "SERVICE", "STATUS", "LAUNCH TYPE", "TASK DEFINITION", "DESIRED", "RUNNING", "PENDING", "ROLLOUT")
+	tasks := output.NewTable("REGION", "CLUSTER", "SERVICE", "TASK", "LAST STATUS", "DESIRED STATUS", "HEALTH", "TASK DEFINITION", "STARTED")
+	for _, result := range results {
+		for _, service := range result.Services {
+			table.Append(result.Region, result.Cluster, service.Name, service.Status, service.LaunchType, service.TaskDefinition,
+				fmt.Sprint(service.Desired), fmt.Sprint(service.Running), fmt.Sprint(service.Pending), service.Rollout)
+			for _, task := range service.Tasks {
+				startedAt := ""
+				if task.StartedAt != nil {
+					startedAt = task.StartedAt.Format(time.RFC3339)
+				}
+				tasks.Append(result.Region, result.Cluster, service.Name, task.ID, task.LastStatus, task.DesiredStatus, task.Health, task.TaskDefinition, startedAt)
+			}
+		}
+	}
+
+	if err := table.Render(os.Stdout); err != nil {
+		return err
+	}
+	if !listTasks {
+		return nil
+	}
+	fmt.Println()
+	return tasks.Render(os.Stdout)
+}
+
+// printServiceTable prints services as a table, highlighting those that aren't settled
+func printServiceTable(services []serviceStatus) {
+	table := output.NewTable("SERVICE", "STATUS", "LAUNCH TYPE", "TASK DEFINITION", "DESIRED", "RUNNING", "PENDING", "ROLLOUT")
+	for _, service := range services {
+		table.Append(service.Name, service.Status, service.LaunchType, service.TaskDefinition,
+			fmt.Sprint(service.Desired), fmt.Sprint(service.Running), fmt.Sprint(service.Pending), service.Rollout)
+	}
+
+	lines := table.Lines()
+	fmt.Println(utils.Bold(lines[0]))
+	for i, service := range services {
+		switch {
+		case service.Rollout == string(types.DeploymentRolloutStateFailed) || (service.Running < service.Desired && service.Pending == 0):
+			fmt.Println(utils.Red(lines[i+1]))
+		case !service.settled():
+			fmt.Println(utils.Yellow(lines[i+1]))
+		default:
+			fmt.Println(lines[i+1])
+		}
+	}
+}
+
+// printTaskTable prints the tasks of services as a table, highlighting unhealthy and stopping tasks
+func printTaskTable(services []serviceStatus) {
+	table := output.NewTable("SERVICE", "TASK", "STATUS", "HEALTH", "TASK DEFINITION", "AGE")
+	var tasks []taskStatus
+	for _, service := range services {
+		for _, task := range service.Tasks {
+			age := ""
+			if task.StartedAt != nil {
+				age = formatAge(time.Since(*task.StartedAt))
+			}
+			table.Append(service.Name, task.ID, task.LastStatus, task.Health, task.TaskDefinition, age)
+			tasks = append(tasks, task)
+		}
+	}
+
+	lines := table.Lines()
+	fmt.Println(utils.Bold(lines[0]))
+	for i, task := range tasks {
+		switch {
+		case task.Health == string(types.HealthStatusUnhealthy):
+			fmt.Println(utils.Red(lines[i+1]))
+		case task.LastStatus != "RUNNING" || task.DesiredStatus != "RUNNING":
+			fmt.Println(utils.Yellow(lines[i+1]))
+		default:
+			fmt.Println(lines[i+1])
+		}
+	}
+}
+
+// formatAge returns a duration the way kubectl shows ages: 45s, 12m, 5h, 3d
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
 }
 
 func init() {
 	containerCmd.AddCommand(containerListCmd)
+	containerListCmd.Flags().StringVar(&listCluster, "cluster", "", "Cluster name (skips the cluster prompt)")
+	containerListCmd.Flags().BoolVar(&listAllRegions, "all-regions", false, "Look for clusters in every configured or enabled region")
+	containerListCmd.Flags().BoolVar(&listTasks, "tasks", false, "Also show the tasks of every service with their health")
+	containerListCmd.Flags().BoolVar(&listAllClusters, "all-clusters", false, "List the services of every cluster instead of prompting for one")
 }
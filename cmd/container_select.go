@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"eaws/internal/state"
+	"eaws/internal/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+// ecsTarget holds the cluster, service, task and container chosen on the command line.
+// Empty fields are selected interactively.
+type ecsTarget struct {
+	Cluster   string
+	Service   string
+	Task      string
+	Container string
+}
+
+// addFlags registers the target selection flags on a command
+func (t *ecsTarget) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&t.Cluster, "cluster", "", "Cluster name (skips the cluster prompt)")
+	cmd.Flags().StringVar(&t.Service, "service", "", "Service name (skips the service prompt)")
+	cmd.Flags().StringVar(&t.Task, "task", "", "Task ID or index starting at 0 (skips the task prompt)")
+	cmd.Flags().StringVar(&t.Container, "container", "", "Container name (skips the container prompt)")
+}
+
+// selectItem returns the item matching value, or prompts for one when value is empty.
+// details, when not nil, are shown next to the items in the prompt.
+// value must match an item exactly, so scripts never act on a similarly named one.
+// Prompts are disabled in machine output modes, where value is required.
+func selectItem(label, kind string, items, details []string, value string) (int, string, error) {
+	if value == "" {
+		if outputFormat.IsMachine() {
+			return 0, "", fmt.Errorf("--%s is required with --output %s. Valid choices: %s", kind, outputFormat, strings.Join(items, ", "))
+		}
+
+		index, err := runSelector(label, items, details, 0)
+		if err != nil {
+			return 0, "", fmt.Errorf("%s selection cancelled: %w", kind, err)
+		}
+		return index, items[index], nil
+	}
+
+	for i, item := range items {
+		if item == value {
+			return i, item, nil
+		}
+	}
+
+	return 0, "", fmt.Errorf("%s '%s' not found. Valid choices: %s", kind, value, strings.Join(items, ", "))
+}
+
+// resourceName returns the last path segment of an ECS ARN
+func resourceName(arn string) string {
+	parts := strings.Split(arn, "/")
+	return parts[len(parts)-1]
+}
+
+// selectCluster returns the cluster named by value or lets the user pick one.
+// When match is not nil only the clusters it accepts are offered, otherwise the clusters of the
+// active context. Without value the context's default cluster is used.
+// It returns an empty name when there are no clusters to choose from.
+func selectCluster(ctx context.Context, client *ecs.Client, value string, match func(string) bool) (string, error) {
+	if value == "" {
+		value = activeContext.Cluster
+	}
+	if match == nil {
+		match = activeContext.MatchCluster
+	}
+
+	stepStart := time.Now()
+	clusterArns, err := utils.ListClusterArns(ctx, client)
+	if err != nil {
+		return "", fmt.Errorf("failed to list clusters: %w", err)
+	}
+	if verbose {
+		utils.PrintInfo(fmt.Sprintf("✓ List clusters: %v", time.Since(stepStart)))
+	}
+
+	var clusterNames []string
+	for _, clusterArn := range clusterArns {
+		name := resourceName(clusterArn)
+		if match(name) {
+			clusterNames = append(clusterNames, name)
+		}
+	}
+
+	if len(clusterNames) == 0 {
+		utils.PrintWarning("No clusters found")
+		return "", nil
+	}
+
+	// Clusters connected to recently come first
+	var details []string
+	if value == "" && !outputFormat.IsMachine() {
+		details = clusterDetails(ctx, client, clusterNames)
+
+		region := client.Options().Region
+		clusterNames, details = recentFirst(clusterNames, details, recentNames(
+			func(t state.Target) bool { return t.Region == region },
+			func(t state.Target) string { return t.Cluster }))
+	}
+
+	_, selectedCluster, err := selectItem("Select cluster", "cluster", clusterNames, details, value)
+	if err != nil {
+		return "", err
+	}
+
+	utils.PrintInfo(fmt.Sprintf("Selected cluster: %s", utils.GreenBold(selectedCluster)))
+	return selectedCluster, nil
+}
+
+// selectService returns the service named by value or lets the user pick one.
+// Without value the default service of the active context is used, unless the context's
+// default cluster is a different one.
+// It returns an empty name when the cluster has no services.
+func selectService(ctx context.Context, client *ecs.Client, cluster, value string) (string, error) {
+	if value == "" && (activeContext.Cluster == "" || activeContext.Cluster == cluster) {
+		value = activeContext.Service
+	}
+
+	stepStart := time.Now()
+	serviceArns, err := utils.ListServiceArns(ctx, client, cluster)
+	if err != nil {
+		return "", fmt.Errorf("failed to list services: %w", err)
+	}
+	if verbose {
+		utils.PrintInfo(fmt.Sprintf("✓ List services: %v", time.Since(stepStart)))
+	}
+
+	if len(serviceArns) == 0 {
+		utils.PrintWarning("No services found in this cluster")
+		return "", nil
+	}
+
+	var serviceNames []string
+	for _, serviceArn := range serviceArns {
+		serviceNames = append(serviceNames, resourceName(serviceArn))
+	}
+
+	// Details are only worth the extra calls when prompting
+	var details []string
+	if value == "" && !outputFormat.IsMachine() {
+		details = serviceDetails(ctx, client, cluster, serviceArns)
+
+		region := client.Options().Region
+		serviceNames, details = recentFirst(serviceNames, details, recentNames(
+			func(t state.Target) bool { return t.Region == region && t.Cluster == cluster },
+			func(t state.Target) string { return t.Service }))
+	}
+
+	_, selectedService, err := selectItem("Select service", "service", serviceNames, details, value)
+	if err != nil {
+		return "", err
+	}
+
+	utils.PrintInfo(fmt.Sprintf("Selected service: %s", utils.GreenBold(selectedService)))
+	return selectedService, nil
+}
+
+// selectTask returns the ARN of the task given by ID or index in value, or lets the user pick one.
+// It returns an empty ARN when the service has no tasks.
+func selectTask(ctx context.Context, client *ecs.Client, cluster, service, value string) (string, error) {
+	stepStart := time.Now()
+	taskArns, err := utils.ListTaskArns(ctx, client, cluster, service)
+	if err != nil {
+		return "", fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if verbose {
+		utils.PrintInfo(fmt.Sprintf("✓ List tasks: %v", time.Since(stepStart)))
+	}
+
+	if len(taskArns) == 0 {
+		utils.PrintWarning("No tasks found for this service")
+		return "", nil
+	}
+
+	// If only one task and none was requested, use it directly
+	if len(taskArns) == 1 && value == "" {
+		utils.PrintInfo(fmt.Sprintf("Using task: %s", utils.GreenBold(taskArns[0])))
+		return taskArns[0], nil
+	}
+
+	var taskIDs []string
+	for _, taskArn := range taskArns {
+		taskIDs = append(taskIDs, resourceName(taskArn))
+	}
+
+	if index, err := strconv.Atoi(value); err == nil {
+		if index < 0 || index >= len(taskArns) {
+			return "", fmt.Errorf("task index %d out of range. Valid choices: 0-%d (%s)", index, len(taskArns)-1, strings.Join(taskIDs, ", "))
+		}
+		utils.PrintInfo(fmt.Sprintf("Selected task: %s", utils.GreenBold(taskArns[index])))
+		return taskArns[index], nil
+	}
+
+	var details []string
+	if value == "" && !outputFormat.IsMachine() {
+		details = taskDetails(ctx, client, cluster, taskArns)
+	}
+
+	taskIndex, _, err := selectItem("Select task", "task", taskIDs, details, value)
+	if err != nil {
+		return "", err
+	}
+
+	utils.PrintInfo(fmt.Sprintf("Selected task: %s", utils.GreenBold(taskArns[taskIndex])))
+	return taskArns[taskIndex], nil
+}
+
+// selectContainer returns the container named by value or lets the user pick one
+func selectContainer(task types.Task, value string) (*types.Container, error) {
+	if len(task.Containers) == 0 {
+		return nil, fmt.Errorf("no containers found in task")
+	}
+
+	// If only one container and none was requested, use it directly
+	if len(task.Containers) == 1 && value == "" {
+		container := &task.Containers[0]
+		utils.PrintInfo(fmt.Sprintf("Using container: %s", utils.GreenBold(*container.Name)))
+		return container, nil
+	}
+
+	var containerNames, details []string
+	for _, container := range task.Containers {
+		if container.Name != nil {
+			containerNames = append(containerNames, *container.Name)
+			details = append(details, joinDetails(aws.ToString(container.LastStatus), string(container.HealthStatus)))
+		}
+	}
+
+	if value == "" {
+		containerNames, details = recentFirst(containerNames, details, recentContainers(task))
+	}
+
+	_, selectedContainerName, err := selectItem("Select container", "container", containerNames, details, value)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range task.Containers {
+		container := &task.Containers[i]
+		if container.Name != nil && *container.Name == selectedContainerName {
+			utils.PrintInfo(fmt.Sprintf("Selected container: %s", utils.GreenBold(selectedContainerName)))
+			return container, nil
+		}
+	}
+
+	return nil, fmt.Errorf("selected container not found")
+}
+
+// clusterDetails describes clusters for the selector, or returns nil when they can't be described
+func clusterDetails(ctx context.Context, client *ecs.Client, names []string) []string {
+	clusters, err := utils.DescribeClusters(ctx, client, names)
+	if err != nil {
+		if verbose {
+			utils.PrintWarning(fmt.Sprintf("Failed to describe clusters: %v", err))
+		}
+		return nil
+	}
+
+	byName := make(map[string]string)
+	for _, cluster := range clusters {
+		summary := joinDetails(aws.ToString(cluster.Status),
+			fmt.Sprintf("%d services", cluster.ActiveServicesCount),
+			fmt.Sprintf("%d running tasks", cluster.RunningTasksCount))
+		if cluster.PendingTasksCount > 0 {
+			summary = joinDetails(summary, fmt.Sprintf("%d pending", cluster.PendingTasksCount))
+		}
+		byName[aws.ToString(cluster.ClusterName)] = summary
+	}
+
+	details := make([]string, len(names))
+	for i, name := range names {
+		details[i] = byName[name]
+	}
+	return details
+}
+
+// serviceDetails describes services for the selector, or returns nil when they can't be described
+func serviceDetails(ctx context.Context, client *ecs.Client, cluster string, serviceArns []string) []string {
+	services, _, err := utils.DescribeServices(ctx, client, cluster, serviceArns)
+	if err != nil {
+		if verbose {
+			utils.PrintWarning(fmt.Sprintf("Failed to describe services: %v", err))
+		}
+		return nil
+	}
+
+	// Describe results may come back in another order, so match them by ARN
+	byArn := make(map[string]string)
+	for _, service := range services {
+		byArn[aws.ToString(service.ServiceArn)] = newServiceStatus(service).summary()
+	}
+
+	details := make([]string, len(serviceArns))
+	for i, arn := range serviceArns {
+		details[i] = byArn[arn]
+	}
+	return details
+}
+
+// taskDetails describes tasks for the selector, or returns nil when they can't be described
+func taskDetails(ctx context.Context, client *ecs.Client, cluster string, taskArns []string) []string {
+	tasks, _, err := utils.DescribeTasks(ctx, client, cluster, taskArns)
+	if err != nil {
+		if verbose {
+			utils.PrintWarning(fmt.Sprintf("Failed to describe tasks: %v", err))
+		}
+		return nil
+	}
+
+	byArn := make(map[string]string)
+	for _, task := range tasks {
+		byArn[aws.ToString(task.TaskArn)] = newTaskStatus(task).summary()
+	}
+
+	details := make([]string, len(taskArns))
+	for i, arn := range taskArns {
+		details[i] = byArn[arn]
+	}
+	return details
+}
+
+// joinDetails joins the non-empty parts of a selector item's details
+func joinDetails(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "  ")
+}
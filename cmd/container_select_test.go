@@ -0,0 +1,40 @@
+package cmd
+
+import "testing"
+
+func TestSelectItem(t *testing.T) {
+	items := []string{"api", "payments-api", "worker"}
+
+	tests := []struct {
+		name      string
+		items     []string
+		value     string
+		wantIndex int
+		wantItem  string
+		wantErr   bool
+	}{
+		{name: "exact match", items: items, value: "payments-api", wantIndex: 1, wantItem: "payments-api"},
+		{name: "exact match that is a substring of another", items: items, value: "api", wantIndex: 0, wantItem: "api"},
+		{name: "substring only", items: []string{"payments-api", "worker"}, value: "api", wantErr: true},
+		{name: "prefix only", items: items, value: "work", wantErr: true},
+		{name: "unknown", items: items, value: "billing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, item, err := selectItem("Select service", "service", tt.items, nil, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("selectItem() = %d, %q, want an error", index, item)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectItem() error = %v", err)
+			}
+			if index != tt.wantIndex || item != tt.wantItem {
+				t.Errorf("selectItem() = %d, %q, want %d, %q", index, item, tt.wantIndex, tt.wantItem)
+			}
+		})
+	}
+}
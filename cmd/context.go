@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eaws/internal/config"
+	"eaws/internal/output"
+	"eaws/internal/state"
+	"eaws/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configFlag  string
+	contextFlag string
+
+	// eawsConfig is the eaws config file, loaded before every command
+	eawsConfig = &config.Config{}
+	// eawsConfigPath is the absolute location eawsConfig was read from
+	eawsConfigPath string
+	// activeContextName is empty when no context is active, in which case activeContext is empty
+	activeContextName string
+	activeContext     = &config.Context{}
+)
+
+// contextCmd represents the context command
+var contextCmd = &cobra.Command{
+	Use:     "context",
+	Aliases: []string{"ctx"},
+	Short:   "Manage eaws contexts",
+	Long: `Manage the named contexts of the eaws config file.
+
+A context holds the defaults of one environment: AWS profile, region, cluster, service and the
+log groups, clusters and pipelines offered in prompts. Flags always win over the active context.
+
+The active context is, in order: --context, EAWS_CONTEXT, the one chosen with 'eaws context use',
+or currentContext in the config file.
+
+Examples:
+  eaws context list
+  eaws context use staging
+  eaws context show prod -o yaml`,
+	Annotations: map[string]string{skipAWSAnnotation: "true"},
+}
+
+// contextListCmd represents the context list command
+var contextListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List contexts",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := eawsConfig.ContextNames()
+
+		if outputFormat.IsMachine() {
+			infos := []contextInfo{}
+			for _, name := range names {
+				infos = append(infos, newContextInfo(name, eawsConfig.Contexts[name]))
+			}
+			return output.Write(os.Stdout, outputFormat, infos)
+		}
+
+		if outputFormat == output.Table {
+			table := output.NewTable("CURRENT", "NAME", "PROFILE", "REGION", "CLUSTER", "SERVICE")
+			for _, name := range names {
+				context := eawsConfig.Contexts[name]
+				current := ""
+				if name == activeContextName {
+					current = "*"
+				}
+				table.Append(current, name, context.Profile, context.Region, context.Cluster, context.Service)
+			}
+			return table.Render(os.Stdout)
+		}
+
+		if len(names) == 0 {
+			utils.PrintWarning(fmt.Sprintf("No contexts defined in %s", eawsConfigPath))
+			return nil
+		}
+
+		for _, name := range names {
+			context := eawsConfig.Contexts[name]
+			if name == activeContextName {
+				fmt.Printf("* %s  %s\n", utils.GreenBold(name), utils.Cyan(contextSummary(context)))
+			} else {
+				fmt.Printf("  %s  %s\n", name, utils.Cyan(contextSummary(context)))
+			}
+		}
+		return nil
+	},
+}
+
+// contextShowCmd represents the context show command
+var contextShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show a context, the active one by default",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := activeContextName
+		if len(args) == 1 {
+			name = args[0]
+		}
+		if name == "" {
+			return fmt.Errorf("no context is active. Use 'eaws context use <name>' or --context")
+		}
+
+		context, err := eawsConfig.Context(name)
+		if err != nil {
+			return err
+		}
+		info := newContextInfo(name, *context)
+
+		if outputFormat.IsMachine() {
+			return output.Write(os.Stdout, outputFormat, info)
+		}
+
+		if outputFormat == output.Table {
+			table := output.NewTable("SETTING", "VALUE")
+			for _, setting := range info.settings() {
+				table.Append(setting[0], setting[1])
+			}
+			return table.Render(os.Stdout)
+		}
+
+		fmt.Printf("%s %s\n", utils.GreenBold("Context:"), utils.GreenBold(name))
+		for _, setting := range info.settings() {
+			if setting[1] != "" {
+				fmt.Printf("  %s: %s\n", setting[0], utils.Cyan(setting[1]))
+			}
+		}
+		return nil
+	},
+}
+
+// contextUseCmd represents the context use command
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make a context the active one",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if _, err := eawsConfig.Context(name); err != nil {
+			return err
+		}
+
+		appState, err := state.Load()
+		if err != nil {
+			return err
+		}
+		appState.SetCurrentContext(eawsConfigPath, name)
+		if err := appState.Save(); err != nil {
+			return fmt.Errorf("failed to save active context: %w", err)
+		}
+
+		utils.PrintSuccess(fmt.Sprintf("Switched to context %s", utils.GreenBold(name)))
+		return nil
+	},
+}
+
+// contextInfo is the machine readable form of a context
+type contextInfo struct {
+	Name             string   `json:"name" yaml:"name"`
+	Current          bool     `json:"current" yaml:"current"`
+	Profile          string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Region           string   `json:"region,omitempty" yaml:"region,omitempty"`
+	Cluster          string   `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Service          string   `json:"service,omitempty" yaml:"service,omitempty"`
+	LogGroupPrefixes []string `json:"logGroupPrefixes,omitempty" yaml:"logGroupPrefixes,omitempty"`
+	Clusters         []string `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+	Pipelines        []string `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+}
+
+func newContextInfo(name string, context config.Context) contextInfo {
+	return contextInfo{
+		Name:             name,
+		Current:          name == activeContextName,
+		Profile:          context.Profile,
+		Region:           context.Region,
+		Cluster:          context.Cluster,
+		Service:          context.Service,
+		LogGroupPrefixes: context.LogGroupPrefixes,
+		Clusters:         context.Clusters,
+		Pipelines:        context.Pipelines,
+	}
+}
+
+// settings returns the settings of the context as name/value pairs, in display order
+func (c contextInfo) settings() [][2]string {
+	join := func(values []string) string {
+		return strings.Join(values, ", ")
+	}
+	return [][2]string{
+		{"profile", c.Profile},
+		{"region", c.Region},
+		{"cluster", c.Cluster},
+		{"service", c.Service},
+		{"logGroupPrefixes", join(c.LogGroupPrefixes)},
+		{"clusters", join(c.Clusters)},
+		{"pipelines", join(c.Pipelines)},
+	}
+}
+
+// contextSummary returns the profile, region, cluster and service of a context on one line
+func contextSummary(context config.Context) string {
+	var parts []string
+	for _, value := range []string{context.Profile, context.Region, context.Cluster, context.Service} {
+		if value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// loadEAWSConfig reads the eaws config file and activates the selected context, whose
+// profile and region become the defaults of --profile and --region
+func loadEAWSConfig() error {
+	configPath := configFlag
+	if configPath == "" {
+		defaultPath, err := config.DefaultPath()
+		if err != nil {
+			return err
+		}
+		configPath = defaultPath
+	} else if _, err := os.Stat(configPath); err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg, err := config.LoadFile(configPath)
+	if err != nil {
+		return err
+	}
+	eawsConfig = cfg
+
+	if absPath, err := filepath.Abs(configPath); err == nil {
+		configPath = absPath
+	}
+	eawsConfigPath = configPath
+
+	// Only --context must name an existing context. A stale selection elsewhere is ignored so
+	// that 'eaws context use' can still fix it.
+	name := contextFlag
+	if name == "" {
+		name = existingContext(os.Getenv("EAWS_CONTEXT"), "EAWS_CONTEXT")
+	}
+	if name == "" {
+		if appState, err := state.Load(); err == nil {
+			name = existingContext(appState.CurrentContext(eawsConfigPath), "The context selected with 'eaws context use'")
+		}
+	}
+	if name == "" {
+		name = existingContext(eawsConfig.CurrentContext, "currentContext")
+	}
+	if name == "" {
+		return nil
+	}
+
+	context, err := eawsConfig.Context(name)
+	if err != nil {
+		return err
+	}
+	activeContextName = name
+	activeContext = context
+
+	if profile == "" {
+		profile = context.Profile
+	}
+	if region == "" {
+		region = context.Region
+	}
+	return nil
+}
+
+// existingContext returns name when the config file defines it. Otherwise it warns that source
+// names a missing context and returns an empty name.
+func existingContext(name, source string) string {
+	if _, found := eawsConfig.Contexts[name]; name != "" && !found {
+		utils.PrintWarning(fmt.Sprintf("%s names context '%s', which no longer exists in %s. Ignoring it", source, name, eawsConfigPath))
+		return ""
+	}
+	return name
+}
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextListCmd)
+	contextCmd.AddCommand(contextShowCmd)
+	contextCmd.AddCommand(contextUseCmd)
+}
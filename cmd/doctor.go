@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"eaws/internal/output"
+	"eaws/internal/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/spf13/cobra"
+)
+
+// checkStatus is the outcome of a doctor check
+type checkStatus string
+
+const (
+	checkPass checkStatus = "pass"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "fail"
+)
+
+// doctorCheck is the result of one doctor check
+type doctorCheck struct {
+	Name        string      `json:"name" yaml:"name"`
+	Status      checkStatus `json:"status" yaml:"status"`
+	Detail      string      `json:"detail" yaml:"detail"`
+	Remediation string      `json:"remediation,omitempty" yaml:"remediation,omitempty"`
+}
+
+// doctorTool is an external program eaws relies on
+type doctorTool struct {
+	Name        string
+	Required    bool
+	Remediation string
+}
+
+var doctorTools = []doctorTool{
+	{
+		Name:        "session-manager-plugin",
+		Required:    true,
+		Remediation: "Install it from https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html",
+	},
+	{
+		Name:        "aws",
+		Remediation: "Optional, eaws doesn't need it. Install it from https://docs.aws.amazon.com/cli/latest/userguide/install-cliv2.html",
+	},
+	{
+		Name:        "assume",
+		Remediation: "Optional, for granted profiles. Install it from https://docs.commonfate.io/granted/getting-started",
+	},
+}
+
+// toolVersionTimeout bounds how long a tool may take to print its version
+const toolVersionTimeout = 5 * time.Second
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment eaws runs in",
+	Long: `Check the tools, AWS configuration, credentials and permissions eaws needs, and explain
+how to fix whatever is missing. The command fails when a required check fails.
+
+Examples:
+  eaws doctor
+  eaws doctor --profile staging -o json`,
+	Args:         cobra.NoArgs,
+	Annotations:  map[string]string{skipAWSAnnotation: "true"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		var checks []doctorCheck
+		for _, tool := range doctorTools {
+			checks = append(checks, checkTool(ctx, tool))
+		}
+		checks = append(checks, checkAWSConfigFile())
+		if check, ok := checkSSOToken(); ok {
+			checks = append(checks, check)
+		}
+
+		cfg, check := checkCredentials(ctx)
+		checks = append(checks, check)
+		if check.Status == checkPass {
+			checks = append(checks, checkECSAccess(ctx, cfg), checkSSMAccess(ctx, cfg))
+		}
+
+		if err := printDoctorChecks(checks); err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, check := range checks {
+			if check.Status == checkFail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+		}
+		return nil
+	},
+}
+
+// checkTool looks for a program in PATH and reports its version
+func checkTool(ctx context.Context, tool doctorTool) doctorCheck {
+	check := doctorCheck{Name: tool.Name}
+
+	path, err := exec.LookPath(tool.Name)
+	if err != nil {
+		check.Status = checkWarn
+		if tool.Required {
+			check.Status = checkFail
+		}
+		check.Detail = "not found in PATH"
+		check.Remediation = tool.Remediation
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, toolVersionTimeout)
+	defer cancel()
+
+	version := "unknown version"
+	if out, err := exec.CommandContext(ctx, path, "--version").CombinedOutput(); err == nil {
+		if line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n"); line != "" {
+			version = line
+		}
+	}
+
+	check.Status = checkPass
+	check.Detail = fmt.Sprintf("%s (%s)", version, path)
+	return check
+}
+
+// checkAWSConfigFile validates the syntax of the shared AWS config file and the selected profile
+func checkAWSConfigFile() doctorCheck {
+	check := doctorCheck{Name: "AWS config file"}
+
+	configFile, err := utils.LoadAWSConfigFile()
+	var syntaxErr *utils.AWSConfigSyntaxError
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("%s does not exist", utils.AWSConfigFilePath())
+		check.Remediation = "Run 'aws configure sso' or 'aws configure' to create a profile"
+		return check
+	case errors.As(err, &syntaxErr):
+		check.Status = checkFail
+		check.Detail = syntaxErr.Error()
+		check.Remediation = "Fix the line in the file; each setting must be 'key = value' inside a [profile name] section"
+		return check
+	case err != nil:
+		check.Status = checkFail
+		check.Detail = err.Error()
+		return check
+	}
+
+	if name := utils.EffectiveProfile(profile); name != "default" {
+		if _, found := configFile.Profile(name); !found {
+			check.Status = checkFail
+			check.Detail = fmt.Sprintf("profile '%s' not found in %s", name, configFile.Path)
+			check.Remediation = "Check the profile name or add it with 'aws configure sso --profile " + name + "'"
+			return check
+		}
+	}
+
+	check.Status = checkPass
+	check.Detail = fmt.Sprintf("%s, %d profiles", configFile.Path, len(configFile.Profiles))
+	return check
+}
+
+// checkSSOToken reports whether the cached SSO token of the selected profile is still valid.
+// ok is false when the profile doesn't use SSO.
+func checkSSOToken() (doctorCheck, bool) {
+	name := utils.EffectiveProfile(profile)
+	sso, ok := utils.ProfileSSOConfig(name)
+	if !ok {
+		return doctorCheck{}, false
+	}
+
+	check := doctorCheck{Name: "SSO session"}
+	remediation := fmt.Sprintf("Run 'eaws login --profile %s'", name)
+
+	token, err := utils.LoadSSOToken(sso)
+	switch {
+	case err != nil:
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("no cached token for %s", sso.StartURL)
+		check.Remediation = remediation
+	case token.Valid():
+		check.Status = checkPass
+		check.Detail = fmt.Sprintf("expires in %s", time.Until(token.Expiration()).Round(time.Minute))
+	case token.Refreshable(sso):
+		check.Status = checkPass
+		check.Detail = "expired, will be refreshed automatically"
+	default:
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("expired at %s", token.Expiration().Local().Format(time.RFC3339))
+		check.Remediation = remediation
+	}
+	return check, true
+}
+
+// checkCredentials loads the AWS configuration and calls STS to verify the credentials
+func checkCredentials(ctx context.Context) (aws.Config, doctorCheck) {
+	check := doctorCheck{Name: "AWS credentials (STS)"}
+
+	cfg, identity, err := utils.LoadAWSConfig(ctx, profile, region)
+	if err != nil {
+		check.Status = checkFail
+		check.Detail = err.Error()
+		var authErr *utils.AWSAuthError
+		if errors.As(err, &authErr) && authErr.Cause != nil {
+			check.Detail = fmt.Sprintf("%s (%v)", authErr.Message, authErr.Cause)
+		}
+		check.Remediation = utils.DetectAWSIssue(profile)
+		return cfg, check
+	}
+
+	check.Status = checkPass
+	check.Detail = fmt.Sprintf("%s as %s in %s", identity.AccountID, identity.Role, identity.Region)
+	return cfg, check
+}
+
+// checkECSAccess verifies that ECS can be listed, which every container command starts with
+func checkECSAccess(ctx context.Context, cfg aws.Config) doctorCheck {
+	check := doctorCheck{Name: "ECS permissions"}
+
+	_, err := ecs.NewFromConfig(cfg).ListClusters(ctx, &ecs.ListClustersInput{MaxResults: aws.Int32(1)})
+	if err != nil {
+		check.Status = checkFail
+		check.Detail = err.Error()
+		check.Remediation = "Grant ecs:ListClusters, ecs:ListServices, ecs:ListTasks, ecs:DescribeTasks and ecs:ExecuteCommand"
+		return check
+	}
+
+	check.Status = checkPass
+	check.Detail = "ecs:ListClusters allowed"
+	return check
+}
+
+// checkSSMAccess verifies that Session Manager can be reached. Starting a session can only be
+// tried by connecting, so this checks a read-only Session Manager call.
+func checkSSMAccess(ctx context.Context, cfg aws.Config) doctorCheck {
+	check := doctorCheck{Name: "SSM permissions"}
+
+	_, err := ssm.NewFromConfig(cfg).DescribeSessions(ctx, &ssm.DescribeSessionsInput{
+		State:      ssmtypes.SessionStateActive,
+		MaxResults: aws.Int32(1),
+	})
+	if err != nil {
+		check.Status = checkFail
+		check.Detail = err.Error()
+		check.Remediation = "Grant ssm:DescribeSessions and ssm:StartSession for container sessions"
+		return check
+	}
+
+	check.Status = checkPass
+	check.Detail = "ssm:DescribeSessions allowed"
+	return check
+}
+
+// printDoctorChecks prints the check results in the selected output format
+func printDoctorChecks(checks []doctorCheck) error {
+	switch outputFormat {
+	case output.JSON, output.YAML:
+		return output.Write(os.Stdout, outputFormat, checks)
+	case output.Table:
+		table := output.NewTable("CHECK", "STATUS", "DETAIL", "REMEDIATION")
+		for _, check := range checks {
+			table.Append(check.Name, string(check.Status), check.Detail, check.Remediation)
+		}
+		return table.Render(os.Stdout)
+	}
+
+	for _, check := range checks {
+		switch check.Status {
+		case checkPass:
+			fmt.Printf("%s %s: %s\n", utils.Green("✓"), utils.Bold(check.Name), check.Detail)
+		case checkWarn:
+			fmt.Printf("%s %s: %s\n", utils.Yellow("⚠"), utils.Bold(check.Name), check.Detail)
+		default:
+			fmt.Printf("%s %s: %s\n", utils.Red("✗"), utils.Bold(check.Name), check.Detail)
+		}
+		if check.Remediation != "" {
+			fmt.Printf("    %s %s\n", utils.Cyan("→"), check.Remediation)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"eaws/internal/utils"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// assumeYes skips the confirmation of actions on protected targets
+var assumeYes bool
+
+// confirmProtected guards container sessions and changes on protected accounts, profiles and
+// clusters: it shows a red banner and asks to type the cluster (or account) name, unless --yes
+// was given. It returns an error when the user doesn't confirm.
+func confirmProtected(cmd *cobra.Command, action, cluster string) error {
+	protected := eawsConfig.Protected
+	identity := callerIdentity(cmd)
+	profileName := utils.EffectiveProfile(profile)
+
+	if len(protected.Accounts) > 0 {
+		lookupAccountAlias(cmd.Context(), awsConfig(cmd), identity, false)
+	}
+
+	var reasons []string
+	if protected.MatchAccount(identity.AccountID, identity.AccountAlias, identity.AliasUnreadable) {
+		if identity.AliasUnreadable && !protected.MatchAccount(identity.AccountID, "", false) {
+			reasons = append(reasons, "account "+identity.AccountID+" (alias unreadable, may be protected)")
+		} else {
+			reasons = append(reasons, "account "+identity.Account())
+		}
+	}
+	if protected.MatchProfile(profileName) {
+		reasons = append(reasons, "profile "+profileName)
+	}
+	if protected.MatchCluster(cluster) {
+		reasons = append(reasons, "cluster "+cluster)
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	utils.PrintWarning(utils.Alert(fmt.Sprintf(" PROTECTED: %s ", strings.Join(reasons, ", "))))
+	utils.PrintWarning(fmt.Sprintf("You are about to %s in %s", action, utils.RedBold(identity.Account())))
+
+	if assumeYes {
+		utils.PrintWarning("Confirmation skipped with --yes")
+		return nil
+	}
+	if outputFormat.IsMachine() || !isTerminal(os.Stdin) {
+		return fmt.Errorf("refusing to %s on a protected target without confirmation. Use --yes to proceed", action)
+	}
+
+	expected := cluster
+	kind := "cluster"
+	if expected == "" {
+		kind = "account"
+		expected = identity.AccountAlias
+		if expected == "" {
+			expected = identity.AccountID
+		}
+	}
+
+	prompt := promptui.Prompt{
+		Label:  fmt.Sprintf("Type the %s name '%s' to continue", kind, expected),
+		Stdout: promptOutput(),
+	}
+	answer, err := prompt.Run()
+	if err != nil {
+		return fmt.Errorf("confirmation cancelled: %w", err)
+	}
+	if strings.TrimSpace(answer) != expected {
+		return fmt.Errorf("%s name doesn't match, aborting", kind)
+	}
+
+	return nil
+}
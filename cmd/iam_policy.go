@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"eaws/internal/output"
+	"eaws/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var policyCommands []string
+
+// iamPolicyCmd represents the iam-policy command
+var iamPolicyCmd = &cobra.Command{
+	Use:   "iam-policy",
+	Short: "Print the IAM policy eaws needs",
+	Long: fmt.Sprintf(`Print the least-privilege IAM policy covering the selected eaws features, one statement
+per feature. Without --commands the policy covers every feature.
+
+Features: %s
+
+Examples:
+  eaws iam-policy
+  eaws iam-policy --commands connect,logs,pipeline > eaws-policy.json
+  eaws iam-policy --commands logs -o table`, strings.Join(utils.FeatureNames(), ", ")),
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{skipAWSAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		features, err := utils.FindFeatures(policyCommands)
+		if err != nil {
+			return err
+		}
+
+		switch outputFormat {
+		case output.YAML:
+			return output.Write(os.Stdout, outputFormat, utils.NewPolicy(features))
+		case output.Table:
+			table := output.NewTable("FEATURE", "ACTION")
+			for _, feature := range features {
+				for _, action := range feature.Actions {
+					table.Append(feature.Name, action)
+				}
+			}
+			return table.Render(os.Stdout)
+		default:
+			// The policy is JSON in text mode too, ready to paste into IAM
+			return output.Write(os.Stdout, output.JSON, utils.NewPolicy(features))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(iamPolicyCmd)
+	iamPolicyCmd.Flags().StringSliceVar(&policyCommands, "commands", nil, "Features to cover, comma separated (default all)")
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"eaws/internal/utils"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// loginCmd represents the login command
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Log in to AWS SSO",
+	Long: `Log in to AWS IAM Identity Center (SSO) with the device authorization flow.
+
+The token is cached in ~/.aws/sso/cache in the same format as 'aws sso login', so the AWS CLI
+and SDKs reuse it. The profile is taken from --profile, AWS_PROFILE or default.
+
+Examples:
+  eaws login --profile staging`,
+	Annotations: map[string]string{skipAWSAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return utils.SSOLogin(cmd.Context(), utils.EffectiveProfile(profile))
+	},
+}
+
+// offerSSOLogin asks to log in when err comes from an expired SSO session and reports
+// whether the login succeeded, in which case the command can be retried
+func offerSSOLogin(ctx context.Context, err error) bool {
+	if outputFormat.IsMachine() || !utils.IsSSOLoginRequired(profile, err) {
+		return false
+	}
+
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Your AWS SSO session for profile '%s' has expired. Log in now", utils.EffectiveProfile(profile)),
+		IsConfirm: true,
+		Default:   "y",
+		Stdout:    promptOutput(),
+	}
+	if _, err := prompt.Run(); err != nil {
+		return false
+	}
+
+	if err := utils.SSOLogin(ctx, utils.EffectiveProfile(profile)); err != nil {
+		utils.PrintError(err.Error())
+		return false
+	}
+	return true
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+}
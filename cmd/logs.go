@@ -4,6 +4,10 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
+
+	"eaws/internal/config"
+
 	"github.com/spf13/cobra"
 )
 
@@ -18,9 +22,32 @@ var logsCmd = &cobra.Command{
 	Short:   "Show logs from CloudWatch",
 	Long: `Show logs from CloudWatch with various options for querying and viewing log streams.
 
+Use --project to only offer the log groups and clusters of a project defined in the eaws config file.
+
 Examples:
-  eaws logs query  # Visualize logs in CloudWatch Insights
-  eaws logs view   # View the logs of the selected container`,
+  eaws logs query             # Visualize logs in CloudWatch Insights
+  eaws logs view              # View the logs of the selected container
+  eaws logs view -j billing   # Only offer the clusters of the billing project`,
+}
+
+// loadProject returns the project selected with --project, or the filters of the active
+// context when none was given
+func loadProject() (*config.Project, error) {
+	if project == "" {
+		return &activeContext.Project, nil
+	}
+	return eawsConfig.Project(project)
+}
+
+// checkLogGroup refuses a log group outside the log group prefixes of the project or context
+func checkLogGroup(logsProject *config.Project, group string) error {
+	if logsProject.MatchLogGroup(group) {
+		return nil
+	}
+	if project == "" {
+		return fmt.Errorf("log group '%s' does not belong to context '%s'", group, activeContextName)
+	}
+	return fmt.Errorf("log group '%s' does not belong to project '%s'", group, project)
 }
 
 func init() {
@@ -1,30 +1,437 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
 
+	"eaws/internal/config"
+	"eaws/internal/output"
 	"eaws/internal/utils"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 	"github.com/spf13/cobra"
 )
 
+var (
+	queryLogGroups []string
+	queryFile      string
+	queryStart     string
+	queryEnd       string
+	querySince     time.Duration
+	queryLimit     int32
+)
+
+// queryPollInterval is how often a running query is checked for results
+const queryPollInterval = time.Second
+
+// defaultQuery is offered in the editor when no query is given
+const defaultQuery = `fields @timestamp, @message
+| sort @timestamp desc
+| limit 20
+`
+
 // logsQueryCmd represents the logs query command
 var logsQueryCmd = &cobra.Command{
-	Use:     "query",
+	Use:     "query [query]",
 	Aliases: []string{"q"},
 	Short:   "Use CloudWatch Insights",
-	Long:    `Use CloudWatch Insights to query logs with a powerful query language.`,
+	Long: `Use CloudWatch Insights to query logs with a powerful query language.
+
+The query is taken from the argument, from --file, or written in $EDITOR when neither is given.
+
+Examples:
+  eaws logs query --log-group /ecs/api 'fields @message | filter @message like /ERROR/'
+  eaws logs query --log-group /ecs/api --log-group /ecs/worker --file errors.insights
+  eaws l q --since 24h`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := utils.CheckAWSProfile(profile); err != nil {
-			return fmt.Errorf("failed to configure AWS profile: %w", err)
+		cfg := awsConfig(cmd)
+
+		logsProject, err := loadProject()
+		if err != nil {
+			return err
 		}
 
-		utils.PrintInfo("CloudWatch Insights query functionality")
-		utils.PrintWarning("This command is not yet implemented")
-		return nil
+		startTime, endTime, err := queryTimeRange()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		client := cloudwatchlogs.NewFromConfig(cfg)
+
+		logGroups := queryLogGroups
+		for _, group := range logGroups {
+			if err := checkLogGroup(logsProject, group); err != nil {
+				return err
+			}
+		}
+		if len(logGroups) == 0 {
+			logGroups, err = selectLogGroups(ctx, client, logsProject)
+			if err != nil {
+				return err
+			}
+		}
+
+		queryString, err := readQuery(args)
+		if err != nil {
+			return err
+		}
+
+		utils.PrintInfo(fmt.Sprintf("Querying %s from %s to %s",
+			utils.GreenBold(strings.Join(logGroups, ", ")),
+			utils.Cyan(startTime.Format(time.RFC3339)),
+			utils.Cyan(endTime.Format(time.RFC3339))))
+
+		startOutput, err := client.StartQuery(ctx, &cloudwatchlogs.StartQueryInput{
+			LogGroupNames: logGroups,
+			QueryString:   aws.String(queryString),
+			StartTime:     aws.Int64(startTime.Unix()),
+			EndTime:       aws.Int64(endTime.Unix()),
+			Limit:         aws.Int32(queryLimit),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start query: %w", err)
+		}
+
+		results, err := waitForQuery(ctx, client, *startOutput.QueryId)
+		if err != nil {
+			return err
+		}
+
+		return printQueryResults(results)
 	},
 }
 
+// queryTimeRange resolves --start, --end and --since into the time range of the query
+func queryTimeRange() (time.Time, time.Time, error) {
+	endTime := time.Now()
+	if queryEnd != "" {
+		parsed, err := parseQueryTime(queryEnd)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --end: %w", err)
+		}
+		endTime = parsed
+	}
+
+	startTime := endTime.Add(-querySince)
+	if queryStart != "" {
+		parsed, err := parseQueryTime(queryStart)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --start: %w", err)
+		}
+		startTime = parsed
+	}
+
+	if !startTime.Before(endTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start time %s is not before end time %s", startTime.Format(time.RFC3339), endTime.Format(time.RFC3339))
+	}
+
+	return startTime, endTime, nil
+}
+
+// parseQueryTime accepts RFC3339 timestamps as well as plain dates and local date-times
+func parseQueryTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a valid time, use RFC3339 (2006-01-02T15:04:05Z) or 2006-01-02 15:04", value)
+}
+
+// selectLogGroups lets the user pick one or more log groups, limited to the project's when one is given
+func selectLogGroups(ctx context.Context, client *cloudwatchlogs.Client, logsProject *config.Project) ([]string, error) {
+	prefixes := []string{""}
+	if logsProject != nil && len(logsProject.LogGroupPrefixes) > 0 {
+		prefixes = logsProject.LogGroupPrefixes
+	}
+
+	var available []string
+	seen := make(map[string]bool)
+	for _, prefix := range prefixes {
+		names, err := utils.ListLogGroupNames(ctx, client, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list log groups: %w", err)
+		}
+		// Overlapping prefixes would otherwise list a group twice
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				available = append(available, name)
+			}
+		}
+	}
+
+	if len(available) == 0 {
+		return nil, fmt.Errorf("no log groups found")
+	}
+
+	if outputFormat.IsMachine() {
+		return nil, fmt.Errorf("--log-group is required with --output %s", outputFormat)
+	}
+
+	var selected []string
+	for len(available) > 0 {
+		label := "Select log group"
+		items := available
+		if len(selected) > 0 {
+			label = fmt.Sprintf("Add another log group (%d selected)", len(selected))
+			items = append([]string{"✓ Done"}, available...)
+		}
+
+		index, err := runSelector(label, items, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("log group selection cancelled: %w", err)
+		}
+		choice := items[index]
+
+		if len(selected) > 0 {
+			if index == 0 {
+				break
+			}
+			index--
+		}
+
+		selected = append(selected, choice)
+		available = append(available[:index:index], available[index+1:]...)
+		utils.PrintInfo(fmt.Sprintf("Selected log group: %s", utils.GreenBold(choice)))
+	}
+
+	return selected, nil
+}
+
+// readQuery returns the query from the argument, --file or $EDITOR, in that order
+func readQuery(args []string) (string, error) {
+	if len(args) > 0 {
+		if queryFile != "" {
+			return "", fmt.Errorf("use either a query argument or --file, not both")
+		}
+		return args[0], nil
+	}
+
+	if queryFile != "" {
+		content, err := os.ReadFile(queryFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read query file: %w", err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	if outputFormat.IsMachine() {
+		return "", fmt.Errorf("a query argument or --file is required with --output %s", outputFormat)
+	}
+
+	return editQuery()
+}
+
+// editQuery opens $EDITOR on a temporary file prefilled with a default query
+func editQuery() (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	// EDITOR may carry arguments, e.g. "code --wait"
+	editorArgs := strings.Fields(editor)
+	if len(editorArgs) == 0 {
+		editor = "vi"
+		editorArgs = []string{editor}
+	}
+
+	file, err := os.CreateTemp("", "eaws-query-*.insights")
+	if err != nil {
+		return "", fmt.Errorf("failed to create query file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(defaultQuery); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write query file: %w", err)
+	}
+	file.Close()
+
+	editorCmd := exec.Command(editorArgs[0], append(editorArgs[1:], file.Name())...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+
+	if err := editorCmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to run editor '%s': %w", editor, err)
+	}
+
+	content, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read query file: %w", err)
+	}
+
+	queryString := strings.TrimSpace(string(content))
+	if queryString == "" {
+		return "", fmt.Errorf("empty query, aborting")
+	}
+	return queryString, nil
+}
+
+// waitForQuery polls a query until it finishes, showing its progress on stderr
+func waitForQuery(ctx context.Context, client *cloudwatchlogs.Client, queryID string) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	spinner := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	started := time.Now()
+
+	ticker := time.NewTicker(queryPollInterval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		output, err := client.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
+			QueryId: aws.String(queryID),
+		})
+		if err != nil {
+			fmt.Fprint(os.Stderr, "\r\033[K")
+			if ctx.Err() != nil {
+				return nil, cancelQuery(client, queryID)
+			}
+			return nil, fmt.Errorf("failed to get query results: %w", err)
+		}
+
+		switch output.Status {
+		case cwtypes.QueryStatusComplete:
+			fmt.Fprint(os.Stderr, "\r\033[K")
+			return output, nil
+		case cwtypes.QueryStatusFailed, cwtypes.QueryStatusCancelled, cwtypes.QueryStatusTimeout:
+			fmt.Fprint(os.Stderr, "\r\033[K")
+			return nil, fmt.Errorf("query finished with status %s", output.Status)
+		}
+
+		var scanned float64
+		if output.Statistics != nil {
+			scanned = output.Statistics.RecordsScanned
+		}
+		fmt.Fprintf(os.Stderr, "\r\033[K%s %s %s, %.0f records scanned",
+			utils.Cyan(spinner[frame%len(spinner)]),
+			output.Status,
+			time.Since(started).Round(time.Second),
+			scanned)
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprint(os.Stderr, "\r\033[K")
+			return nil, cancelQuery(client, queryID)
+		case <-ticker.C:
+		}
+	}
+}
+
+// cancelQuery stops a running query so it doesn't keep scanning (and billing) after Ctrl+C
+func cancelQuery(client *cloudwatchlogs.Client, queryID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.StopQuery(ctx, &cloudwatchlogs.StopQueryInput{QueryId: aws.String(queryID)}); err != nil {
+		return fmt.Errorf("query interrupted, failed to stop it: %w", err)
+	}
+	return fmt.Errorf("query interrupted")
+}
+
+// queryResult is the machine readable result of a query
+type queryResult struct {
+	Results    []map[string]string `json:"results" yaml:"results"`
+	Statistics queryStatistics     `json:"statistics" yaml:"statistics"`
+}
+
+// queryStatistics reports how much data a query scanned, which is what Insights bills for
+type queryStatistics struct {
+	RecordsMatched float64 `json:"recordsMatched" yaml:"recordsMatched"`
+	RecordsScanned float64 `json:"recordsScanned" yaml:"recordsScanned"`
+	BytesScanned   float64 `json:"bytesScanned" yaml:"bytesScanned"`
+}
+
+// printQueryResults renders query results as a table with one column per field, followed by
+// the query statistics, or encodes them in machine output modes
+func printQueryResults(results *cloudwatchlogs.GetQueryResultsOutput) error {
+	var statistics queryStatistics
+	if results.Statistics != nil {
+		statistics = queryStatistics{
+			RecordsMatched: results.Statistics.RecordsMatched,
+			RecordsScanned: results.Statistics.RecordsScanned,
+			BytesScanned:   results.Statistics.BytesScanned,
+		}
+	}
+
+	// Keep the field order of the query, skipping the internal @ptr field
+	var columns []string
+	seen := map[string]bool{"@ptr": true}
+	rows := make([]map[string]string, 0, len(results.Results))
+	for _, row := range results.Results {
+		values := make(map[string]string, len(row))
+		for _, field := range row {
+			name := aws.ToString(field.Field)
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+			if name != "@ptr" {
+				values[name] = aws.ToString(field.Value)
+			}
+		}
+		rows = append(rows, values)
+	}
+
+	if outputFormat.IsMachine() {
+		return output.Write(os.Stdout, outputFormat, queryResult{Results: rows, Statistics: statistics})
+	}
+
+	if len(rows) == 0 {
+		utils.PrintWarning("No results")
+	} else {
+		table := output.NewTable(columns...)
+		for _, values := range rows {
+			cells := make([]string, len(columns))
+			for i, column := range columns {
+				cells[i] = values[column]
+			}
+			table.Append(cells...)
+		}
+		if err := table.Render(os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	if outputFormat == output.Table {
+		return nil
+	}
+
+	fmt.Printf("\n%s\n", utils.GreenBold("Query statistics:"))
+	fmt.Printf("  Rows returned:   %d\n", len(rows))
+	fmt.Printf("  Records matched: %.0f\n", statistics.RecordsMatched)
+	fmt.Printf("  Records scanned: %.0f\n", statistics.RecordsScanned)
+	fmt.Printf("  Data scanned:    %s\n", utils.Yellow(formatBytes(statistics.BytesScanned)))
+	return nil
+}
+
+// formatBytes formats a byte count with binary units
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[unit])
+}
+
 func init() {
 	logsCmd.AddCommand(logsQueryCmd)
+	logsQueryCmd.Flags().StringArrayVarP(&queryLogGroups, "log-group", "g", nil, "Log group to query (repeatable, prompts when omitted)")
+	logsQueryCmd.Flags().StringVar(&queryFile, "file", "", "Read the query from a file")
+	logsQueryCmd.Flags().StringVar(&queryStart, "start", "", "Start of the time range (RFC3339 or 2006-01-02 15:04)")
+	logsQueryCmd.Flags().StringVar(&queryEnd, "end", "", "End of the time range (defaults to now)")
+	logsQueryCmd.Flags().DurationVar(&querySince, "since", time.Hour, "Query this far back from the end when --start is not set")
+	logsQueryCmd.Flags().Int32Var(&queryLimit, "limit", 1000, "Maximum number of results to return")
 }
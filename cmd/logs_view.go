@@ -1,30 +1,260 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"time"
 
 	"eaws/internal/utils"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/spf13/cobra"
 )
 
+var (
+	viewTarget ecsTarget
+	viewFollow bool
+	viewSince  time.Duration
+	viewTail   int
+)
+
+// followInterval is how often new events are polled for with --follow
+const followInterval = 2 * time.Second
+
+// logStream identifies the CloudWatch log stream of a container
+type logStream struct {
+	Group  string
+	Name   string
+	Region string
+}
+
 // logsViewCmd represents the logs view command
 var logsViewCmd = &cobra.Command{
 	Use:     "view",
 	Aliases: []string{"v"},
 	Short:   "Show log stream",
-	Long:    `Show log stream for the selected container or service.`,
+	Long: `Show the CloudWatch log stream of the selected container.
+
+The log group and stream are resolved from the awslogs configuration of the task definition.
+
+Examples:
+  eaws logs view --cluster prod --service api
+  eaws logs view --since 15m
+  eaws l v --tail 50 -f`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := utils.CheckAWSProfile(profile); err != nil {
-			return fmt.Errorf("failed to configure AWS profile: %w", err)
+		cfg := awsConfig(cmd)
+
+		logsProject, err := loadProject()
+		if err != nil {
+			return err
 		}
 
-		utils.PrintInfo("Log stream viewing functionality")
-		utils.PrintWarning("This command is not yet implemented")
-		return nil
+		// Stop following on Ctrl+C instead of killing the process mid-write
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		ecsClient := ecs.NewFromConfig(cfg)
+		loadRecentTargets(cmd)
+
+		selectedCluster, err := selectCluster(ctx, ecsClient, viewTarget.Cluster, logsProject.MatchCluster)
+		if err != nil || selectedCluster == "" {
+			return err
+		}
+
+		selectedService, err := selectService(ctx, ecsClient, selectedCluster, viewTarget.Service)
+		if err != nil || selectedService == "" {
+			return err
+		}
+
+		selectedTask, err := selectTask(ctx, ecsClient, selectedCluster, selectedService, viewTarget.Task)
+		if err != nil || selectedTask == "" {
+			return err
+		}
+
+		describeOutput, err := ecsClient.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: &selectedCluster,
+			Tasks:   []string{selectedTask},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe task: %w", err)
+		}
+
+		if len(describeOutput.Tasks) == 0 {
+			return fmt.Errorf("no task details found")
+		}
+
+		task := describeOutput.Tasks[0]
+
+		selectedContainer, err := selectContainer(task, viewTarget.Container)
+		if err != nil {
+			return err
+		}
+
+		stream, err := resolveLogStream(ctx, ecsClient, task, *selectedContainer.Name)
+		if err != nil {
+			return err
+		}
+		if err := checkLogGroup(logsProject, stream.Group); err != nil {
+			return err
+		}
+		if stream.Region == "" {
+			stream.Region = cfg.Region
+		}
+
+		utils.PrintInfo(fmt.Sprintf("Log stream: %s", utils.GreenBold(stream.Group+" "+stream.Name)))
+
+		logsClient := cloudwatchlogs.NewFromConfig(cfg, func(o *cloudwatchlogs.Options) {
+			o.Region = stream.Region
+		})
+
+		return tailLogStream(ctx, logsClient, stream)
 	},
 }
 
+// resolveLogStream finds the awslogs log group and stream of a container from its task definition
+func resolveLogStream(ctx context.Context, client *ecs.Client, task types.Task, containerName string) (logStream, error) {
+	taskDefinitionOutput, err := client.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: task.TaskDefinitionArn,
+	})
+	if err != nil {
+		return logStream{}, fmt.Errorf("failed to describe task definition: %w", err)
+	}
+
+	var logConfiguration *types.LogConfiguration
+	for _, definition := range taskDefinitionOutput.TaskDefinition.ContainerDefinitions {
+		if aws.ToString(definition.Name) == containerName {
+			logConfiguration = definition.LogConfiguration
+			break
+		}
+	}
+
+	if logConfiguration == nil {
+		return logStream{}, fmt.Errorf("container '%s' has no log configuration", containerName)
+	}
+
+	if logConfiguration.LogDriver != types.LogDriverAwslogs {
+		return logStream{}, fmt.Errorf("container '%s' uses the %s log driver, only awslogs is supported", containerName, logConfiguration.LogDriver)
+	}
+
+	group := logConfiguration.Options["awslogs-group"]
+	if group == "" {
+		return logStream{}, fmt.Errorf("container '%s' has no awslogs-group option", containerName)
+	}
+
+	// Without a prefix the stream is named after the Docker container ID, which ECS does not expose
+	prefix := logConfiguration.Options["awslogs-stream-prefix"]
+	if prefix == "" {
+		return logStream{}, fmt.Errorf("container '%s' has no awslogs-stream-prefix option, so its log stream cannot be resolved", containerName)
+	}
+
+	return logStream{
+		Group:  group,
+		Name:   fmt.Sprintf("%s/%s/%s", prefix, containerName, resourceName(*task.TaskArn)),
+		Region: logConfiguration.Options["awslogs-region"],
+	}, nil
+}
+
+// tailLogStream prints the requested events of a stream and keeps polling for new ones with --follow
+func tailLogStream(ctx context.Context, client *cloudwatchlogs.Client, stream logStream) error {
+	input := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(stream.Group),
+		LogStreamName: aws.String(stream.Name),
+	}
+
+	var events []cwtypes.OutputLogEvent
+	var token *string
+
+	if viewSince > 0 || viewTail < 0 {
+		// Read forward from the start time and keep the last --tail events
+		input.StartFromHead = aws.Bool(true)
+		if viewSince > 0 {
+			input.StartTime = aws.Int64(time.Now().Add(-viewSince).UnixMilli())
+		}
+
+		for {
+			output, err := client.GetLogEvents(ctx, input)
+			if err != nil {
+				return fmt.Errorf("failed to get log events: %w", err)
+			}
+			events = append(events, output.Events...)
+			if viewTail >= 0 && len(events) > viewTail {
+				events = events[len(events)-viewTail:]
+			}
+
+			token = output.NextForwardToken
+			// The forward token stops changing once the end of the stream is reached
+			if aws.ToString(token) == aws.ToString(input.NextToken) {
+				break
+			}
+			input.NextToken = token
+		}
+	} else {
+		// Without a start time the last --tail events fit in a single backward read
+		input.StartFromHead = aws.Bool(false)
+		input.Limit = aws.Int32(int32(max(1, min(viewTail, 10000))))
+
+		output, err := client.GetLogEvents(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to get log events: %w", err)
+		}
+		if viewTail > 0 {
+			events = output.Events
+		}
+		token = output.NextForwardToken
+	}
+
+	printLogEvents(events)
+
+	if !viewFollow {
+		return nil
+	}
+
+	input.StartTime = nil
+	input.Limit = nil
+	input.StartFromHead = aws.Bool(true)
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		input.NextToken = token
+		output, err := client.GetLogEvents(ctx, input)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to get log events: %w", err)
+		}
+
+		printLogEvents(output.Events)
+		token = output.NextForwardToken
+	}
+}
+
+// printLogEvents prints log events with their timestamp
+func printLogEvents(events []cwtypes.OutputLogEvent) {
+	for _, event := range events {
+		timestamp := time.UnixMilli(aws.ToInt64(event.Timestamp)).Format(time.RFC3339)
+		fmt.Printf("%s %s\n", utils.Cyan(timestamp), aws.ToString(event.Message))
+	}
+}
+
 func init() {
 	logsCmd.AddCommand(logsViewCmd)
+	viewTarget.addFlags(logsViewCmd)
+	logsViewCmd.Flags().BoolVarP(&viewFollow, "follow", "f", false, "Keep streaming new log events")
+	logsViewCmd.Flags().DurationVar(&viewSince, "since", 0, "Only show events newer than this duration, e.g. 15m or 2h")
+	logsViewCmd.Flags().IntVar(&viewTail, "tail", 100, "Number of most recent events to show (-1 for all)")
 }
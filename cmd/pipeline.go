@@ -3,69 +3,94 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"strings"
+	"os"
 	"time"
 
+	"eaws/internal/output"
 	"eaws/internal/utils"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
 	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
-	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
 
-var debug bool
+var (
+	debug              bool
+	pipelineName       string
+	pipelineAllRegions bool
+)
 
 // pipelineCmd represents the pipeline command
 var pipelineCmd = &cobra.Command{
 	Use:     "pipeline",
 	Aliases: []string{"p"},
 	Short:   "Show status pipeline",
-	Long:    `Show the status of CodePipeline pipelines with detailed information about stages and actions.`,
+	Long: `Show the status of CodePipeline pipelines with detailed information about stages and actions.
+
+Examples:
+  eaws pipeline --pipeline api-deploy
+  eaws p --pipeline api-deploy -o json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := utils.CheckAWSProfile(profile); err != nil {
-			return fmt.Errorf("failed to configure AWS profile: %w", err)
-		}
+		cfg := awsConfig(cmd)
 
-		cfg, err := utils.LoadAWSConfig(profile)
+		ctx := cmd.Context()
+
+		regions, err := targetRegions(ctx, cfg, pipelineAllRegions)
 		if err != nil {
-			return fmt.Errorf("failed to load AWS config: %w", err)
+			return err
 		}
 
-		client := codepipeline.NewFromConfig(cfg)
-		ctx := context.Background()
+		// List pipelines in every region
+		results := utils.InRegions(ctx, cfg, regions, func(ctx context.Context, regionCfg aws.Config) ([]types.PipelineSummary, error) {
+			return utils.ListPipelines(ctx, codepipeline.NewFromConfig(regionCfg))
+		})
 
-		// List pipelines
-		pipelinesOutput, err := client.ListPipelines(ctx, &codepipeline.ListPipelinesInput{})
-		if err != nil {
-			return fmt.Errorf("failed to list pipelines: %w", err)
+		var pipelines []regionalName
+		for _, result := range results {
+			if result.Err != nil {
+				if len(regions) == 1 {
+					return fmt.Errorf("failed to list pipelines: %w", result.Err)
+				}
+				utils.PrintWarning(fmt.Sprintf("Skipping region %s: failed to list pipelines: %v", result.Region, result.Err))
+				continue
+			}
+			for _, pipeline := range result.Value {
+				// Only offer the pipelines of the active context
+				if pipeline.Name != nil && activeContext.MatchPipeline(*pipeline.Name) {
+					pipelines = append(pipelines, regionalName{Region: result.Region, Name: *pipeline.Name})
+				}
+			}
 		}
 
-		if len(pipelinesOutput.Pipelines) == 0 {
+		if len(pipelines) == 0 {
 			utils.PrintWarning("No pipelines found")
 			return nil
 		}
 
-		// Extract pipeline names
+		// Extract pipeline names, labelled by region when several regions were visited
 		var pipelineNames []string
-		for _, pipeline := range pipelinesOutput.Pipelines {
-			if pipeline.Name != nil {
-				pipelineNames = append(pipelineNames, *pipeline.Name)
-			}
+		for _, pipeline := range pipelines {
+			pipelineNames = append(pipelineNames, pipeline.label(len(regions) > 1))
 		}
 
-		// Interactive pipeline selection
-		prompt := promptui.Select{
-			Label: "Select pipeline",
-			Items: pipelineNames,
+		// Execution status is only worth the extra calls when prompting
+		var details []string
+		if pipelineName == "" && !outputFormat.IsMachine() {
+			details = pipelineDetails(ctx, cfg, pipelines)
 		}
 
-		_, selectedPipeline, err := prompt.Run()
+		pipelineIndex, _, err := selectItem("Select pipeline", "pipeline", pipelineNames, details, pipelineName)
 		if err != nil {
-			return fmt.Errorf("pipeline selection cancelled: %w", err)
+			return err
 		}
 
-		utils.PrintInfo(fmt.Sprintf("Selected pipeline: %s", utils.GreenBold(selectedPipeline)))
+		selectedPipeline := pipelines[pipelineIndex].Name
+		client := codepipeline.NewFromConfig(cfg, func(o *codepipeline.Options) {
+			o.Region = pipelines[pipelineIndex].Region
+		})
+
+		utils.PrintInfo(fmt.Sprintf("Selected pipeline: %s", utils.GreenBold(pipelineNames[pipelineIndex])))
 
 		// Get pipeline state
 		stateOutput, err := client.GetPipelineState(ctx, &codepipeline.GetPipelineStateInput{
@@ -75,6 +100,10 @@ var pipelineCmd = &cobra.Command{
 			return fmt.Errorf("failed to get pipeline state: %w", err)
 		}
 
+		if outputFormat != output.Text {
+			return printPipelineStatus(newPipelineStatus(pipelines[pipelineIndex].Region, stateOutput))
+		}
+
 		// Display pipeline information
 		if !debug {
 			// Show summary view
@@ -112,82 +141,62 @@ var pipelineCmd = &cobra.Command{
 			}
 		} else {
 			// Show detailed view with interactive selection
-			var stageNames []string
+			var stages []types.StageState
+			var stageNames, stageDetails []string
 			for _, stage := range stateOutput.StageStates {
 				if stage.StageName != nil {
-					stageName := *stage.StageName
 					stageStatus := "Unknown"
 					if stage.LatestExecution != nil && stage.LatestExecution.Status != "" {
 						stageStatus = string(stage.LatestExecution.Status)
 					}
-					stageNames = append(stageNames, fmt.Sprintf("%s - %s", stageName, stageStatus))
+					stages = append(stages, stage)
+					stageNames = append(stageNames, *stage.StageName)
+					stageDetails = append(stageDetails, stageStatus)
 				}
 			}
 
-			// Interactive stage selection
-			stagePrompt := promptui.Select{
-				Label: "Select stage to inspect",
-				Items: stageNames,
+			if len(stages) == 0 {
+				return fmt.Errorf("stage not found")
 			}
 
-			_, selectedStageItem, err := stagePrompt.Run()
+			// Interactive stage selection
+			stageIndex, err := runSelector("Select stage to inspect", stageNames, stageDetails, 0)
 			if err != nil {
 				return fmt.Errorf("stage selection cancelled: %w", err)
 			}
-
-			stageName := strings.Split(selectedStageItem, " - ")[0]
-
-			// Find the selected stage
-			var selectedStage *types.StageState
-			for _, stage := range stateOutput.StageStates {
-				if stage.StageName != nil && *stage.StageName == stageName {
-					selectedStage = &stage
-					break
-				}
-			}
-
-			if selectedStage == nil {
-				return fmt.Errorf("stage not found")
-			}
+			selectedStage := stages[stageIndex]
 
 			// Show actions for selected stage
-			var actionNames []string
+			var actions []types.ActionState
+			var actionNames, actionDetails []string
 			for _, action := range selectedStage.ActionStates {
 				if action.ActionName != nil {
-					actionName := *action.ActionName
 					actionStatus := "Unknown"
-					if action.LatestExecution != nil && action.LatestExecution.Status != "" {
-						actionStatus = string(action.LatestExecution.Status)
+					lastChange := ""
+					if action.LatestExecution != nil {
+						if action.LatestExecution.Status != "" {
+							actionStatus = string(action.LatestExecution.Status)
+						}
+						if action.LatestExecution.LastStatusChange != nil {
+							lastChange = formatAge(time.Since(*action.LatestExecution.LastStatusChange)) + " ago"
+						}
 					}
-					actionNames = append(actionNames, fmt.Sprintf("%s - %s", actionName, actionStatus))
+					actions = append(actions, action)
+					actionNames = append(actionNames, *action.ActionName)
+					actionDetails = append(actionDetails, joinDetails(actionStatus, lastChange))
 				}
 			}
 
-			// Interactive action selection
-			actionPrompt := promptui.Select{
-				Label: "Select action to inspect",
-				Items: actionNames,
+			if len(actions) == 0 {
+				return fmt.Errorf("action not found")
 			}
 
-			_, selectedActionItem, err := actionPrompt.Run()
+			// Interactive action selection
+			actionIndex, err := runSelector("Select action to inspect", actionNames, actionDetails, 0)
 			if err != nil {
 				return fmt.Errorf("action selection cancelled: %w", err)
 			}
-
-			actionName := strings.Split(selectedActionItem, " - ")[0]
-
-			// Find the selected action
-			var selectedAction *types.ActionState
-			for _, action := range selectedStage.ActionStates {
-				if action.ActionName != nil && *action.ActionName == actionName {
-					selectedAction = &action
-					break
-				}
-			}
-
-			if selectedAction == nil {
-				return fmt.Errorf("action not found")
-			}
+			selectedAction := actions[actionIndex]
 
 			// Display detailed action information
 			fmt.Printf("\n%s\n", utils.GreenBold("Action Details:"))
@@ -211,7 +220,140 @@ var pipelineCmd = &cobra.Command{
 	},
 }
 
+// pipelineStatusParallelism bounds how many pipelines are asked for their last execution at once
+const pipelineStatusParallelism = 8
+
+// pipelineDetails returns the status and age of the latest execution of every pipeline for the
+// selector, leaving out those that can't be read
+func pipelineDetails(ctx context.Context, cfg aws.Config, pipelines []regionalName) []string {
+	results := utils.Parallel(ctx, pipelines, pipelineStatusParallelism, func(ctx context.Context, pipeline regionalName) (string, error) {
+		client := codepipeline.NewFromConfig(cfg, func(o *codepipeline.Options) {
+			o.Region = pipeline.Region
+		})
+		output, err := client.ListPipelineExecutions(ctx, &codepipeline.ListPipelineExecutionsInput{
+			PipelineName: aws.String(pipeline.Name),
+			MaxResults:   aws.Int32(1),
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(output.PipelineExecutionSummaries) == 0 {
+			return "never run", nil
+		}
+
+		execution := output.PipelineExecutionSummaries[0]
+		age := ""
+		if execution.LastUpdateTime != nil {
+			age = formatAge(time.Since(*execution.LastUpdateTime)) + " ago"
+		}
+		return joinDetails(string(execution.Status), age), nil
+	})
+
+	details := make([]string, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			if verbose {
+				utils.PrintWarning(fmt.Sprintf("Failed to get the last execution of %s: %v", result.Item.Name, result.Err))
+			}
+			continue
+		}
+		details[i] = result.Value
+	}
+	return details
+}
+
+// pipelineStatus is the machine readable state of a pipeline
+type pipelineStatus struct {
+	Name    string        `json:"name" yaml:"name"`
+	Region  string        `json:"region" yaml:"region"`
+	Version int32         `json:"version" yaml:"version"`
+	Stages  []stageStatus `json:"stages" yaml:"stages"`
+}
+
+// stageStatus is the state of a pipeline stage
+type stageStatus struct {
+	Name    string         `json:"name" yaml:"name"`
+	Status  string         `json:"status" yaml:"status"`
+	Actions []actionStatus `json:"actions" yaml:"actions"`
+}
+
+// actionStatus is the state of a stage action
+type actionStatus struct {
+	Name             string     `json:"name" yaml:"name"`
+	Status           string     `json:"status" yaml:"status"`
+	LastStatusChange *time.Time `json:"lastStatusChange,omitempty" yaml:"lastStatusChange,omitempty"`
+	Error            string     `json:"error,omitempty" yaml:"error,omitempty"`
+	URL              string     `json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+// newPipelineStatus converts a GetPipelineState response, using "Unknown" for missing statuses
+func newPipelineStatus(region string, state *codepipeline.GetPipelineStateOutput) pipelineStatus {
+	status := pipelineStatus{
+		Name:    aws.ToString(state.PipelineName),
+		Region:  region,
+		Version: aws.ToInt32(state.PipelineVersion),
+		Stages:  []stageStatus{},
+	}
+
+	for _, stage := range state.StageStates {
+		stageResult := stageStatus{
+			Name:    aws.ToString(stage.StageName),
+			Status:  "Unknown",
+			Actions: []actionStatus{},
+		}
+		if stage.LatestExecution != nil && stage.LatestExecution.Status != "" {
+			stageResult.Status = string(stage.LatestExecution.Status)
+		}
+
+		for _, action := range stage.ActionStates {
+			actionResult := actionStatus{
+				Name:   aws.ToString(action.ActionName),
+				Status: "Unknown",
+				URL:    aws.ToString(action.EntityUrl),
+			}
+			if execution := action.LatestExecution; execution != nil {
+				if execution.Status != "" {
+					actionResult.Status = string(execution.Status)
+				}
+				actionResult.LastStatusChange = execution.LastStatusChange
+				if execution.ErrorDetails != nil {
+					actionResult.Error = aws.ToString(execution.ErrorDetails.Message)
+				}
+			}
+			stageResult.Actions = append(stageResult.Actions, actionResult)
+		}
+
+		status.Stages = append(status.Stages, stageResult)
+	}
+
+	return status
+}
+
+// printPipelineStatus prints a pipeline in one of the non-text output formats
+func printPipelineStatus(status pipelineStatus) error {
+	if outputFormat.IsMachine() {
+		return output.Write(os.Stdout, outputFormat, status)
+	}
+
+	table := output.NewTable("STAGE", "STAGE STATUS", "ACTION", "ACTION STATUS", "LAST CHANGE")
+	for _, stage := range status.Stages {
+		if len(stage.Actions) == 0 {
+			table.Append(stage.Name, stage.Status, "", "", "")
+		}
+		for _, action := range stage.Actions {
+			lastChange := ""
+			if action.LastStatusChange != nil {
+				lastChange = action.LastStatusChange.Format(time.RFC3339)
+			}
+			table.Append(stage.Name, stage.Status, action.Name, action.Status, lastChange)
+		}
+	}
+	return table.Render(os.Stdout)
+}
+
 func init() {
 	rootCmd.AddCommand(pipelineCmd)
 	pipelineCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Inspect each step")
+	pipelineCmd.Flags().StringVar(&pipelineName, "pipeline", "", "Pipeline name (skips the pipeline prompt)")
+	pipelineCmd.Flags().BoolVar(&pipelineAllRegions, "all-regions", false, "Look for pipelines in every configured or enabled region")
 }
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"eaws/internal/state"
+	"eaws/internal/utils"
+)
+
+// profileChoice is an AWS profile offered by the profile picker
+type profileChoice struct {
+	Name      string
+	AccountID string
+	Role      string
+	Region    string
+}
+
+// pickProfile lets the user choose an AWS profile when none is given with --profile or the
+// environment. The choice is remembered per working directory and preselected next time.
+// It returns an empty name when there is nothing to choose or no terminal to ask on.
+func pickProfile() (string, error) {
+	if os.Getenv("AWS_PROFILE") != "" || os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return "", nil
+	}
+	if outputFormat.IsMachine() || !isTerminal(os.Stdin) {
+		return "", nil
+	}
+
+	configFile, err := utils.LoadAWSConfigFile()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			utils.PrintWarning(fmt.Sprintf("Failed to read AWS profiles: %v", err))
+		}
+		return "", nil
+	}
+
+	// A lone default profile is what the SDK uses anyway
+	if len(configFile.Profiles) == 0 || (len(configFile.Profiles) == 1 && configFile.Profiles[0].Name == "default") {
+		return "", nil
+	}
+
+	var choices []profileChoice
+	for _, section := range configFile.Profiles {
+		choices = append(choices, profileChoice{
+			Name:      section.Name,
+			AccountID: section.AccountID(),
+			Role:      section.RoleName(),
+			Region:    section.Get("region"),
+		})
+	}
+
+	workDir, _ := os.Getwd()
+	appState, err := state.Load()
+	if err != nil {
+		utils.PrintWarning(fmt.Sprintf("Failed to read eaws state: %v", err))
+		appState = &state.State{}
+	}
+
+	cursor := 0
+	last := appState.LastProfile(workDir)
+	for i, choice := range choices {
+		if choice.Name == last {
+			cursor = i
+		}
+	}
+
+	var names, details []string
+	for _, choice := range choices {
+		names = append(names, choice.Name)
+		details = append(details, joinDetails(choice.AccountID, choice.Role, choice.Region))
+	}
+
+	index, err := runSelector("Select AWS profile", names, details, cursor)
+	if err != nil {
+		return "", fmt.Errorf("profile selection cancelled: %w", err)
+	}
+
+	selected := choices[index].Name
+	appState.SetLastProfile(workDir, selected)
+	if err := appState.Save(); err != nil {
+		utils.PrintWarning(fmt.Sprintf("Failed to remember AWS profile: %v", err))
+	}
+
+	return selected, nil
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
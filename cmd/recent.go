@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"eaws/internal/state"
+	"eaws/internal/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+// recentTargets are the targets last connected to in the current account, most recent first.
+// Selectors offer their clusters, services and containers first.
+var recentTargets []state.Target
+
+// loadRecentTargets reads the connection history of the account the command runs in
+func loadRecentTargets(cmd *cobra.Command) {
+	identity := callerIdentity(cmd)
+	if identity == nil {
+		return
+	}
+
+	appState, err := state.Load()
+	if err != nil {
+		utils.PrintWarning(fmt.Sprintf("Failed to read eaws state: %v", err))
+		return
+	}
+	recentTargets = appState.RecentTargets(identity.AccountID)
+}
+
+// rememberTarget adds target to the connection history of the account the command runs in
+func rememberTarget(cmd *cobra.Command, target state.Target) {
+	identity := callerIdentity(cmd)
+	if identity == nil {
+		return
+	}
+
+	appState, err := state.Load()
+	if err != nil {
+		utils.PrintWarning(fmt.Sprintf("Failed to read eaws state: %v", err))
+		return
+	}
+
+	target.At = time.Now()
+	appState.AddRecentTarget(identity.AccountID, target)
+	if err := appState.Save(); err != nil {
+		utils.PrintWarning(fmt.Sprintf("Failed to remember connection: %v", err))
+	}
+}
+
+// lastTarget returns the target connected to most recently, in region unless it is empty
+func lastTarget(region string) (state.Target, bool) {
+	for _, target := range recentTargets {
+		if region == "" || target.Region == region {
+			return target, true
+		}
+	}
+	return state.Target{}, false
+}
+
+// recentNames returns the distinct names taken by name from the recent targets keep accepts,
+// most recent first
+func recentNames(keep func(state.Target) bool, name func(state.Target) string) []string {
+	var names []string
+	for _, target := range recentTargets {
+		if keep(target) && !slices.Contains(names, name(target)) {
+			names = append(names, name(target))
+		}
+	}
+	return names
+}
+
+// recentContainers returns the containers of the task's service connected to recently
+func recentContainers(task types.Task) []string {
+	// Task ARNs have the form arn:aws:ecs:<region>:<account>:task/<cluster>/<id>
+	parts := strings.Split(aws.ToString(task.TaskArn), ":")
+	if len(parts) < 6 {
+		return nil
+	}
+	region := parts[3]
+	cluster := resourceName(aws.ToString(task.ClusterArn))
+	service := strings.TrimPrefix(aws.ToString(task.Group), "service:")
+
+	return recentNames(
+		func(t state.Target) bool { return t.Region == region && t.Cluster == cluster && t.Service == service },
+		func(t state.Target) string { return t.Container })
+}
+
+// recentFirst moves the items named in recent to the top of a selector, most recent first,
+// and marks them as recent in their details
+func recentFirst(items, details, recent []string) ([]string, []string) {
+	if len(recent) == 0 {
+		return items, details
+	}
+
+	detail := func(i int) string {
+		if i < len(details) {
+			return details[i]
+		}
+		return ""
+	}
+
+	var sortedItems, sortedDetails []string
+	for _, name := range recent {
+		if i := slices.Index(items, name); i >= 0 {
+			sortedItems = append(sortedItems, name)
+			sortedDetails = append(sortedDetails, joinDetails("★ recent", detail(i)))
+		}
+	}
+	for i, item := range items {
+		if !slices.Contains(recent, item) {
+			sortedItems = append(sortedItems, item)
+			sortedDetails = append(sortedDetails, detail(i))
+		}
+	}
+	return sortedItems, sortedDetails
+}
+
+// freshTask returns taskID when the task still runs in the service, or else the ID of its
+// most recently started running task. It returns an empty ID when no task is running.
+func freshTask(ctx context.Context, client *ecs.Client, cluster, service, taskID string) (string, error) {
+	taskArns, err := utils.ListTaskArns(ctx, client, cluster, service)
+	if err != nil {
+		return "", fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	for _, taskArn := range taskArns {
+		if resourceName(taskArn) == taskID {
+			return taskID, nil
+		}
+	}
+
+	tasks, _, err := utils.DescribeTasks(ctx, client, cluster, taskArns)
+	if err != nil {
+		return "", fmt.Errorf("failed to describe tasks: %w", err)
+	}
+
+	var fresh string
+	var freshStart time.Time
+	for _, task := range tasks {
+		if aws.ToString(task.LastStatus) != "RUNNING" || task.StartedAt == nil {
+			continue
+		}
+		if fresh == "" || task.StartedAt.After(freshStart) {
+			fresh = resourceName(aws.ToString(task.TaskArn))
+			freshStart = *task.StartedAt
+		}
+	}
+
+	if fresh != "" && taskID != "" {
+		utils.PrintInfo(fmt.Sprintf("Task %s is gone, using the newest running task %s", taskID, utils.GreenBold(fresh)))
+	}
+	return fresh, nil
+}
+
+// describeTarget names a target for messages
+func describeTarget(target state.Target) string {
+	return strings.Join([]string{target.Cluster, target.Service, target.Container}, "/") + " (" + target.Region + ")"
+}
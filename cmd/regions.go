@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"eaws/internal/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// targetRegions returns the regions a command should visit: the configured region list
+// (or every enabled region) with --all-regions, and the region of cfg otherwise
+func targetRegions(ctx context.Context, cfg aws.Config, allRegions bool) ([]string, error) {
+	if !allRegions {
+		return []string{cfg.Region}, nil
+	}
+
+	if len(eawsConfig.Regions) > 0 {
+		return eawsConfig.Regions, nil
+	}
+
+	regions, err := utils.ListEnabledRegions(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list regions: %w", err)
+	}
+	return regions, nil
+}
+
+// regionalName is the name of a resource together with the region it lives in
+type regionalName struct {
+	Region string
+	Name   string
+}
+
+// label returns the name, suffixed with its region when results span several regions
+func (r regionalName) label(withRegion bool) string {
+	if withRegion {
+		return fmt.Sprintf("%s (%s)", r.Name, r.Region)
+	}
+	return r.Name
+}
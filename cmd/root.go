@@ -4,13 +4,38 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"eaws/internal/output"
+	"eaws/internal/state"
+	"eaws/internal/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/spf13/cobra"
 )
 
+// skipAWSAnnotation marks commands (and their subcommands) that run without AWS credentials
+const skipAWSAnnotation = "eaws.skip-aws"
+
+// noBannerAnnotation marks commands that don't print the caller identity banner
+const noBannerAnnotation = "eaws.no-banner"
+
+// awsConfigKey is the context key of the AWS configuration shared with subcommands
+type awsConfigKey struct{}
+
+// callerIdentityKey is the context key of the identity the AWS credentials belong to
+type callerIdentityKey struct{}
+
 var (
-	verbose bool
-	profile string
-	version = "0.2.0"
+	verbose      bool
+	profile      string
+	region       string
+	outputFlag   string
+	outputFormat = output.Text
+	version      = "0.2.0"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -21,16 +46,160 @@ var rootCmd = &cobra.Command{
 
 This tool is designed to simplify common AWS operations with interactive prompts and colorized output.`,
 	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.Parse(outputFlag)
+		if err != nil {
+			return err
+		}
+		outputFormat = format
+
+		// Keep stdout clean for programs and pipes reading the results
+		if outputFormat != output.Text {
+			utils.SetMessageOutput(os.Stderr)
+		}
+
+		if err := loadEAWSConfig(); err != nil {
+			return err
+		}
+
+		// Flags are valid by now, so errors come from AWS and the usage wouldn't help
+		cmd.SilenceUsage = true
+
+		if !needsAWS(cmd) {
+			return nil
+		}
+
+		if activeContextName != "" {
+			utils.PrintInfo(fmt.Sprintf("Using context: %s", utils.GreenBold(activeContextName)))
+		}
+
+		if profile == "" {
+			if profile, err = pickProfile(); err != nil {
+				return err
+			}
+		}
+
+		// Load and verify credentials once per process; subcommands read them from the context
+		startTime := time.Now()
+		cfg, identity, err := utils.LoadAWSConfig(cmd.Context(), profile, region)
+		if err != nil && offerSSOLogin(cmd.Context(), err) {
+			cfg, identity, err = utils.LoadAWSConfig(cmd.Context(), profile, region)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to configure AWS profile: %w", err)
+		}
+		if verbose {
+			utils.PrintInfo(fmt.Sprintf("✓ AWS profile check: %v", time.Since(startTime)))
+		}
+
+		// Say which account is about to be touched before anything happens in it
+		if (verbose || (outputFormat == output.Text && isTerminal(os.Stdin))) && cmd.Annotations[noBannerAnnotation] != "true" {
+			lookupAccountAlias(cmd.Context(), cfg, identity, false)
+			utils.PrintInfo(identity.Banner())
+		}
+
+		ctx := context.WithValue(cmd.Context(), awsConfigKey{}, cfg)
+		cmd.SetContext(context.WithValue(ctx, callerIdentityKey{}, identity))
+		return nil
+	},
+}
+
+// needsAWS reports whether cmd talks to AWS. Commands without a run function, cobra's
+// help and completion commands, and commands annotated with skipAWSAnnotation don't.
+func needsAWS(cmd *cobra.Command) bool {
+	if !cmd.Runnable() {
+		return false
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[skipAWSAnnotation] == "true" {
+			return false
+		}
+		if c.Name() == "help" || c.Name() == "completion" || c.Name() == cobra.ShellCompRequestCmd {
+			return false
+		}
+	}
+	return true
+}
+
+// awsConfig returns the AWS configuration loaded for the running command
+func awsConfig(cmd *cobra.Command) aws.Config {
+	cfg, _ := cmd.Context().Value(awsConfigKey{}).(aws.Config)
+	return cfg
+}
+
+// callerIdentity returns the identity the AWS credentials of the running command belong to
+func callerIdentity(cmd *cobra.Command) *utils.CallerIdentity {
+	identity, _ := cmd.Context().Value(callerIdentityKey{}).(*utils.CallerIdentity)
+	return identity
+}
+
+// lookupAccountAlias fills in the account alias of identity. Aliases are remembered per account
+// in the state file so IAM is only asked once, unless refresh is set. Accounts whose alias
+// can't be read for lack of permission are remembered as such; other failures are retried
+// next time. identity.AliasUnreadable tells an unreadable alias from an account without one.
+func lookupAccountAlias(ctx context.Context, cfg aws.Config, identity *utils.CallerIdentity, refresh bool) {
+	var cached string
+	var known, denied bool
+	appState, stateErr := state.Load()
+	if stateErr == nil {
+		cached, known = appState.AccountAlias(identity.AccountID)
+		denied = appState.AccountAliasDenied(identity.AccountID)
+	}
+	if (known || denied) && !refresh {
+		identity.AccountAlias = cached
+		identity.AliasUnreadable = denied
+		return
+	}
+
+	identity.AccountAlias = ""
+	err := identity.LookupAccountAlias(ctx, cfg)
+	identity.AliasUnreadable = err != nil
+	if err != nil && utils.ClassifyAWSError(err) != utils.CategoryAccessDenied {
+		if verbose {
+			utils.PrintWarning(fmt.Sprintf("Failed to get the account alias: %v", err))
+		}
+		identity.AccountAlias = cached
+		identity.AliasUnreadable = !known
+		return
+	}
+	if stateErr != nil {
+		return
+	}
+
+	if identity.AliasUnreadable {
+		if denied {
+			return
+		}
+		appState.SetAccountAliasDenied(identity.AccountID)
+	} else {
+		if known && cached == identity.AccountAlias {
+			return
+		}
+		appState.SetAccountAlias(identity.AccountID, identity.AccountAlias)
+	}
+	if err := appState.Save(); err != nil {
+		utils.PrintWarning(fmt.Sprintf("Failed to remember account alias: %v", err))
+	}
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if err != nil {
+		// Every command explains AWS failures the same way, whatever wrapped them
+		utils.ExplainAWSError(err, profile)
+	}
+	return err
 }
 
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print everything")
 	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "p", "", "AWS profile to use")
+	rootCmd.PersistentFlags().StringVarP(&region, "region", "r", "", "AWS region to use")
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "eaws config file (default ~/.config/eaws/config.yaml, or EAWS_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&contextFlag, "context", "", "eaws context to use (default EAWS_CONTEXT or the one chosen with 'eaws context use')")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Skip the confirmation on protected accounts, profiles and clusters")
+	rootCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", string(output.Text), "Output format: text, table, json or yaml")
 }
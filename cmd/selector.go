@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"eaws/internal/output"
+
+	"github.com/manifoldco/promptui"
+)
+
+// selectorSize is how many items a selector shows at once
+const selectorSize = 15
+
+// selectorKeys are the key bindings of every selector: arrows move and page, Ctrl+T toggles
+// search. A printable key would clash with names that contain it, such as "/ecs/api", and
+// readline reports the right arrow as Ctrl+F.
+var selectorKeys = &promptui.SelectKeys{
+	Prev:     promptui.Key{Code: promptui.KeyPrev, Display: promptui.KeyPrevDisplay},
+	Next:     promptui.Key{Code: promptui.KeyNext, Display: promptui.KeyNextDisplay},
+	PageUp:   promptui.Key{Code: promptui.KeyBackward, Display: promptui.KeyBackwardDisplay},
+	PageDown: promptui.Key{Code: promptui.KeyForward, Display: promptui.KeyForwardDisplay},
+	Search:   promptui.Key{Code: 0x14, Display: "Ctrl+T"}, // ASCII DC4, sent by Ctrl+T
+}
+
+// selectorTemplates render an item as its name followed by its details
+var selectorTemplates = &promptui.SelectTemplates{
+	Label:    "{{ . }}",
+	Active:   "▸ {{ .Name | cyan | bold }}{{ .Padding }}  {{ .Details | faint }}",
+	Inactive: "  {{ .Name }}{{ .Padding }}  {{ .Details | faint }}",
+	Selected: `{{ "✔" | green }} {{ .Name | faint }}`,
+	Help:     `{{ "Type to filter, ↑ ↓ to move, ← → to page, Ctrl+T to toggle search, enter to select" | faint }}`,
+}
+
+// selectorRow is an item as shown by a selector, its name padded so the details line up
+type selectorRow struct {
+	Name    string
+	Padding string
+	Details string
+}
+
+// runSelector lets the user pick one of items with fuzzy search and returns its index.
+// details, when not nil, holds the text shown next to each item; cursor is the item
+// highlighted first.
+func runSelector(label string, items, details []string, cursor int) (int, error) {
+	width := 0
+	for _, item := range items {
+		width = max(width, utf8.RuneCountInString(item))
+	}
+
+	rows := make([]selectorRow, len(items))
+	for i, item := range items {
+		rows[i] = selectorRow{Name: item, Padding: strings.Repeat(" ", width-utf8.RuneCountInString(item))}
+		if i < len(details) {
+			rows[i].Details = details[i]
+		}
+	}
+
+	prompt := promptui.Select{
+		Label:     label,
+		Items:     rows,
+		Size:      selectorSize,
+		Keys:      selectorKeys,
+		Templates: selectorTemplates,
+		Searcher: func(input string, index int) bool {
+			row := rows[index]
+			return fuzzyMatch(input, row.Name) || strings.Contains(strings.ToLower(row.Details), strings.ToLower(strings.TrimSpace(input)))
+		},
+		StartInSearchMode: true,
+		Stdout:            promptOutput(),
+	}
+
+	index, _, err := prompt.RunCursorAt(cursor, max(0, cursor-selectorSize+1))
+	return index, err
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in text in the same order,
+// ignoring case and spaces, so "prdapi" matches "prod-api"
+func fuzzyMatch(pattern, text string) bool {
+	text = strings.ToLower(text)
+	for _, r := range strings.ToLower(pattern) {
+		if r == ' ' {
+			continue
+		}
+		i := strings.IndexRune(text, r)
+		if i < 0 {
+			return false
+		}
+		text = text[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+// promptOutput returns where prompts are drawn: stderr when stdout carries results for other
+// programs, and nil for promptui's default of stdout otherwise
+func promptOutput() io.WriteCloser {
+	if outputFormat != output.Text {
+		return os.Stderr
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// serviceCmd represents the service command
+var serviceCmd = &cobra.Command{
+	Use:     "service",
+	Aliases: []string{"svc"},
+	Short:   "Helper command to manage ECS services",
+	Long:    `Helper command to manage ECS services with operations like restarting them.`,
+}
+
+func init() {
+	rootCmd.AddCommand(serviceCmd)
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"eaws/internal/output"
+	"eaws/internal/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restartCluster string
+	restartService string
+	restartTimeout time.Duration
+)
+
+// restartPollInterval is how often service restart checks on the deployment
+const restartPollInterval = 5 * time.Second
+
+// serviceRestartCmd represents the service restart command
+var serviceRestartCmd = &cobra.Command{
+	Use:     "restart",
+	Aliases: []string{"r"},
+	Short:   "Restart a service with a new deployment",
+	Long: `Restart an ECS service by forcing a new deployment, which replaces every task with a new
+one running the same task definition, and watch the rollout until it completes or fails.
+
+Service events and task counts are shown while the deployment progresses. The command exits
+with an error when the deployment fails, is rolled back or doesn't finish within --timeout.
+Stopping the watch with Ctrl+C leaves the deployment running.
+
+Examples:
+  eaws service restart --cluster prod --service api
+  eaws svc r --service api --timeout 20m
+  eaws svc r --cluster prod --service api --yes -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := awsConfig(cmd)
+		loadRecentTargets(cmd)
+
+		ecsClient := ecs.NewFromConfig(cfg)
+		ctx := cmd.Context()
+
+		selectedCluster, err := selectCluster(ctx, ecsClient, restartCluster, nil)
+		if err != nil || selectedCluster == "" {
+			return err
+		}
+
+		selectedService, err := selectService(ctx, ecsClient, selectedCluster, restartService)
+		if err != nil || selectedService == "" {
+			return err
+		}
+
+		// A restart must never land on a service other than the one named on the command line
+		if restartCluster != "" && selectedCluster != restartCluster {
+			return fmt.Errorf("cluster '%s' not found", restartCluster)
+		}
+		if restartService != "" && selectedService != restartService {
+			return fmt.Errorf("service '%s' not found in cluster %s", restartService, selectedCluster)
+		}
+
+		if err := confirmProtected(cmd, fmt.Sprintf("restart service %s", selectedService), selectedCluster); err != nil {
+			return err
+		}
+
+		updateOutput, err := ecsClient.UpdateService(ctx, &ecs.UpdateServiceInput{
+			Cluster:            &selectedCluster,
+			Service:            &selectedService,
+			ForceNewDeployment: true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update service: %w", err)
+		}
+
+		deployment, found := primaryDeployment(updateOutput.Service)
+		if !found {
+			return fmt.Errorf("no deployment started for service %s", selectedService)
+		}
+		deploymentID := aws.ToString(deployment.Id)
+		utils.PrintSuccess(fmt.Sprintf("Started deployment %s", utils.GreenBold(deploymentID)))
+
+		started := time.Now()
+		if deployment.CreatedAt != nil {
+			started = *deployment.CreatedAt
+		}
+
+		// Stop watching on Ctrl+C or timeout; the deployment goes on either way
+		watchCtx, cancel := context.WithTimeout(ctx, restartTimeout)
+		defer cancel()
+		watchCtx, stop := signal.NotifyContext(watchCtx, os.Interrupt)
+		defer stop()
+
+		result, err := watchDeployment(watchCtx, ecsClient, selectedCluster, selectedService, deploymentID, started)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				return fmt.Errorf("deployment %s didn't finish within %s. It continues in the background", deploymentID, restartTimeout)
+			case errors.Is(err, context.Canceled):
+				return fmt.Errorf("stopped watching deployment %s before it finished. It continues in the background", deploymentID)
+			}
+			return err
+		}
+
+		if outputFormat != output.Text {
+			if err := printRestartResult(result); err != nil {
+				return err
+			}
+		}
+
+		if result.RolloutState != string(types.DeploymentRolloutStateCompleted) {
+			reason := result.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("%d tasks failed to start", result.Failed)
+			}
+			return fmt.Errorf("deployment %s of service %s failed: %s", deploymentID, selectedService, reason)
+		}
+
+		utils.PrintSuccess(fmt.Sprintf("Service %s restarted in %s", utils.GreenBold(selectedService), time.Since(started).Round(time.Second)))
+		return nil
+	},
+}
+
+// restartResult is the outcome of service restart
+type restartResult struct {
+	Cluster      string `json:"cluster" yaml:"cluster"`
+	Service      string `json:"service" yaml:"service"`
+	Deployment   string `json:"deployment" yaml:"deployment"`
+	RolloutState string `json:"rolloutState" yaml:"rolloutState"`
+	Reason       string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Desired      int32  `json:"desired" yaml:"desired"`
+	Running      int32  `json:"running" yaml:"running"`
+	Pending      int32  `json:"pending" yaml:"pending"`
+	Failed       int32  `json:"failedTasks" yaml:"failedTasks"`
+}
+
+// newRestartResult converts a deployment of a DescribeServices result
+func newRestartResult(cluster, service string, deployment types.Deployment) restartResult {
+	return restartResult{
+		Cluster:      cluster,
+		Service:      service,
+		Deployment:   aws.ToString(deployment.Id),
+		RolloutState: string(deployment.RolloutState),
+		Reason:       aws.ToString(deployment.RolloutStateReason),
+		Desired:      deployment.DesiredCount,
+		Running:      deployment.RunningCount,
+		Pending:      deployment.PendingCount,
+		Failed:       deployment.FailedTasks,
+	}
+}
+
+// progress describes the state of the deployment in one line
+func (r restartResult) progress() string {
+	state := r.RolloutState
+	if state == "" {
+		state = "IN_PROGRESS"
+	}
+
+	failed := fmt.Sprintf("%d failed", r.Failed)
+	if r.Failed > 0 {
+		failed = utils.Red(failed)
+	}
+	return fmt.Sprintf("%s %d/%d running, %d pending, %s", utils.YellowBold(state), r.Running, r.Desired, r.Pending, failed)
+}
+
+// primaryDeployment returns the deployment a service is rolling out
+func primaryDeployment(service *types.Service) (types.Deployment, bool) {
+	if service == nil {
+		return types.Deployment{}, false
+	}
+	for _, deployment := range service.Deployments {
+		if aws.ToString(deployment.Status) == "PRIMARY" {
+			return deployment, true
+		}
+	}
+	return types.Deployment{}, false
+}
+
+// watchDeployment polls a service, printing its new events and the progress of the deployment,
+// until the deployment completes or fails. It returns the context's error when stopped early.
+func watchDeployment(ctx context.Context, client *ecs.Client, cluster, service, deploymentID string, since time.Time) (restartResult, error) {
+	seenEvents := make(map[string]bool)
+	lastProgress := ""
+
+	ticker := time.NewTicker(restartPollInterval)
+	defer ticker.Stop()
+
+	for {
+		describeOutput, err := client.DescribeServices(ctx, &ecs.DescribeServicesInput{
+			Cluster:  &cluster,
+			Services: []string{service},
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return restartResult{}, ctx.Err()
+			}
+			return restartResult{}, fmt.Errorf("failed to describe service: %w", err)
+		}
+		if len(describeOutput.Services) == 0 {
+			return restartResult{}, fmt.Errorf("service %s not found", service)
+		}
+		current := describeOutput.Services[0]
+
+		// Events are listed newest first
+		for i := len(current.Events) - 1; i >= 0; i-- {
+			event := current.Events[i]
+			id := aws.ToString(event.Id)
+			if seenEvents[id] || event.CreatedAt == nil || event.CreatedAt.Before(since) {
+				continue
+			}
+			seenEvents[id] = true
+			utils.PrintInfo(fmt.Sprintf("%s %s", utils.Cyan(event.CreatedAt.Local().Format("15:04:05")), aws.ToString(event.Message)))
+		}
+
+		var deployment *types.Deployment
+		for i := range current.Deployments {
+			if aws.ToString(current.Deployments[i].Id) == deploymentID {
+				deployment = &current.Deployments[i]
+			}
+		}
+
+		// A rollback or another deployment took over
+		if deployment == nil {
+			return restartResult{
+				Cluster:      cluster,
+				Service:      service,
+				Deployment:   deploymentID,
+				RolloutState: string(types.DeploymentRolloutStateFailed),
+				Reason:       "the deployment was replaced by a newer one",
+			}, nil
+		}
+
+		result := newRestartResult(cluster, service, *deployment)
+		if progress := result.progress(); progress != lastProgress {
+			utils.PrintInfo(fmt.Sprintf("[%s] %s", time.Since(since).Round(time.Second), progress))
+			lastProgress = progress
+		}
+
+		switch deployment.RolloutState {
+		case types.DeploymentRolloutStateCompleted, types.DeploymentRolloutStateFailed:
+			return result, nil
+		case "":
+			// Services without the ECS deployment controller report no rollout state
+			if len(current.Deployments) == 1 && deployment.RunningCount == deployment.DesiredCount {
+				result.RolloutState = string(types.DeploymentRolloutStateCompleted)
+				return result, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// printRestartResult prints the outcome of a restart in the selected output format
+func printRestartResult(result restartResult) error {
+	if outputFormat.IsMachine() {
+		return output.Write(os.Stdout, outputFormat, result)
+	}
+
+	table := output.NewTable("CLUSTER", "SERVICE", "DEPLOYMENT", "ROLLOUT", "DESIRED", "RUNNING", "PENDING", "FAILED TASKS", "REASON")
+	table.Append(result.Cluster, result.Service, result.Deployment, result.RolloutState,
+		fmt.Sprint(result.Desired), fmt.Sprint(result.Running), fmt.Sprint(result.Pending), fmt.Sprint(result.Failed), result.Reason)
+	return table.Render(os.Stdout)
+}
+
+func init() {
+	serviceCmd.AddCommand(serviceRestartCmd)
+	serviceRestartCmd.Flags().StringVar(&restartCluster, "cluster", "", "Cluster name (skips the cluster prompt)")
+	serviceRestartCmd.Flags().StringVar(&restartService, "service", "", "Service name (skips the service prompt)")
+	serviceRestartCmd.Flags().DurationVar(&restartTimeout, "timeout", 30*time.Minute, "How long to watch the deployment before giving up")
+}
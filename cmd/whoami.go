@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"eaws/internal/output"
+	"eaws/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// whoamiCmd represents the whoami command
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the AWS account, role and region in use",
+	Long: `Show the AWS account ID and alias, the assumed role and the region eaws acts in.
+
+Examples:
+  eaws whoami
+  eaws whoami --profile prod -o json`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{noBannerAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		identity := callerIdentity(cmd)
+		lookupAccountAlias(cmd.Context(), awsConfig(cmd), identity, true)
+
+		switch outputFormat {
+		case output.JSON, output.YAML:
+			return output.Write(os.Stdout, outputFormat, identity)
+		case output.Table:
+			table := output.NewTable("ACCOUNT", "ALIAS", "ROLE", "REGION", "ARN")
+			table.Append(identity.AccountID, identity.AccountAlias, identity.Role, identity.Region, identity.ARN)
+			return table.Render(os.Stdout)
+		default:
+			fmt.Printf("%s %s\n", utils.GreenBold("Account:"), utils.YellowBold(identity.Account()))
+			fmt.Printf("%s %s\n", utils.GreenBold("Role:   "), identity.Role)
+			fmt.Printf("%s %s\n", utils.GreenBold("Region: "), identity.Region)
+			fmt.Printf("%s %s\n", utils.GreenBold("ARN:    "), utils.Cyan(identity.ARN))
+			return nil
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the eaws configuration file
+type Config struct {
+	// Regions are the regions visited by --all-regions. All enabled regions are used when empty.
+	Regions  []string           `yaml:"regions,omitempty"`
+	Projects map[string]Project `yaml:"projects,omitempty"`
+	// CurrentContext is the context used when none is selected with --context, EAWS_CONTEXT
+	// or 'eaws context use'
+	CurrentContext string             `yaml:"currentContext,omitempty"`
+	Contexts       map[string]Context `yaml:"contexts,omitempty"`
+	Protected      Protected          `yaml:"protected,omitempty"`
+}
+
+// Protected lists the accounts, profiles and clusters where connecting or changing anything
+// must be confirmed
+type Protected struct {
+	// Accounts are account IDs or aliases. When the alias of the current account can't be
+	// read, any alias listed here requires confirmation.
+	Accounts []string `yaml:"accounts,omitempty"`
+	// Profiles are AWS profile names, shell patterns allowed
+	Profiles []string `yaml:"profiles,omitempty"`
+	// Clusters are ECS cluster names, shell patterns allowed
+	Clusters []string `yaml:"clusters,omitempty"`
+}
+
+// Context holds the defaults of one environment, used when flags don't say otherwise
+type Context struct {
+	Profile string `yaml:"profile,omitempty"`
+	Region  string `yaml:"region,omitempty"`
+	// Cluster and Service skip the cluster and service prompts
+	Cluster string `yaml:"cluster,omitempty"`
+	Service string `yaml:"service,omitempty"`
+	// Project filters the log groups, clusters and pipelines offered in prompts
+	Project `yaml:",inline"`
+}
+
+// Project groups the AWS resources that belong to one project
+type Project struct {
+	// LogGroupPrefixes are the name prefixes of the project's CloudWatch log groups
+	LogGroupPrefixes []string `yaml:"logGroupPrefixes,omitempty"`
+	// Clusters are the names of the project's ECS clusters, shell patterns allowed
+	Clusters []string `yaml:"clusters,omitempty"`
+	// Pipelines are the names of the project's CodePipeline pipelines, shell patterns allowed
+	Pipelines []string `yaml:"pipelines,omitempty"`
+}
+
+// DefaultPath returns the location of the configuration file, honoring EAWS_CONFIG
+func DefaultPath() (string, error) {
+	if configPath := os.Getenv("EAWS_CONFIG"); configPath != "" {
+		return configPath, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "eaws", "config.yaml"), nil
+	}
+
+	// ~/.config is used on macOS too, rather than ~/Library/Application Support
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "eaws", "config.yaml"), nil
+}
+
+// Load reads the configuration file. A missing file yields an empty configuration.
+func Load() (*Config, error) {
+	configPath, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(configPath)
+}
+
+// LoadFile reads the configuration from configPath. A missing file yields an empty configuration.
+func LoadFile(configPath string) (*Config, error) {
+	cfg := &Config{}
+
+	content, err := os.ReadFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+
+	return cfg, nil
+}
+
+// Project returns the project with the given name
+func (c *Config) Project(name string) (*Project, error) {
+	project, ok := c.Projects[name]
+	if !ok {
+		if len(c.Projects) == 0 {
+			return nil, fmt.Errorf("project '%s' not found: no projects are defined in the eaws config file", name)
+		}
+		return nil, fmt.Errorf("project '%s' not found. Valid choices: %s", name, strings.Join(c.ProjectNames(), ", "))
+	}
+	return &project, nil
+}
+
+// Context returns the context with the given name
+func (c *Config) Context(name string) (*Context, error) {
+	context, ok := c.Contexts[name]
+	if !ok {
+		if len(c.Contexts) == 0 {
+			return nil, fmt.Errorf("context '%s' not found: no contexts are defined in the eaws config file", name)
+		}
+		return nil, fmt.Errorf("context '%s' not found. Valid choices: %s", name, strings.Join(c.ContextNames(), ", "))
+	}
+	return &context, nil
+}
+
+// ContextNames returns the names of all contexts, sorted
+func (c *Config) ContextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProjectNames returns the names of all projects, sorted
+func (c *Config) ProjectNames() []string {
+	names := make([]string, 0, len(c.Projects))
+	for name := range c.Projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MatchCluster reports whether a cluster belongs to the project.
+// A project without clusters matches every cluster.
+func (p *Project) MatchCluster(name string) bool {
+	return matchAny(p.Clusters, name)
+}
+
+// MatchPipeline reports whether a pipeline belongs to the project.
+// A project without pipelines matches every pipeline.
+func (p *Project) MatchPipeline(name string) bool {
+	return matchAny(p.Pipelines, name)
+}
+
+// MatchLogGroup reports whether a log group belongs to the project.
+// A project without log group prefixes matches every log group.
+func (p *Project) MatchLogGroup(name string) bool {
+	if len(p.LogGroupPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range p.LogGroupPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAccount reports whether the account with the given ID or alias is protected. When the
+// alias couldn't be read, any protected alias matches, so protection fails closed; an account
+// without an alias only matches by ID.
+func (p *Protected) MatchAccount(id, alias string, aliasUnreadable bool) bool {
+	for _, account := range p.Accounts {
+		if account == id {
+			return true
+		}
+		if isAccountID(account) {
+			continue
+		}
+		if aliasUnreadable || (alias != "" && account == alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAccountID reports whether s looks like a 12-digit AWS account ID
+func isAccountID(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchProfile reports whether an AWS profile is protected
+func (p *Protected) MatchProfile(name string) bool {
+	return name != "" && matchPattern(p.Profiles, name)
+}
+
+// MatchCluster reports whether an ECS cluster is protected
+func (p *Protected) MatchCluster(name string) bool {
+	return name != "" && matchPattern(p.Clusters, name)
+}
+
+// matchAny reports whether name matches one of the shell patterns, or patterns is empty
+func matchAny(patterns []string, name string) bool {
+	return len(patterns) == 0 || matchPattern(patterns, name)
+}
+
+// matchPattern reports whether name matches one of the shell patterns
+func matchPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package config
+
+import "testing"
+
+func TestProtectedMatchAccount(t *testing.T) {
+	protected := Protected{Accounts: []string{"123456789012", "acme-prod"}}
+	idsOnly := Protected{Accounts: []string{"123456789012"}}
+
+	tests := []struct {
+		name            string
+		protected       Protected
+		id              string
+		alias           string
+		aliasUnreadable bool
+		want            bool
+	}{
+		{name: "ID match", protected: protected, id: "123456789012", want: true},
+		{name: "ID match with another alias", protected: protected, id: "123456789012", alias: "acme-dev", want: true},
+		{name: "alias match", protected: protected, id: "210987654321", alias: "acme-prod", want: true},
+		{name: "other alias", protected: protected, id: "210987654321", alias: "acme-dev", want: false},
+		{name: "no alias", protected: protected, id: "210987654321", want: false},
+		{name: "unreadable alias", protected: protected, id: "210987654321", aliasUnreadable: true, want: true},
+		{name: "unreadable alias without protected aliases", protected: idsOnly, id: "210987654321", aliasUnreadable: true, want: false},
+		{name: "nothing protected", protected: Protected{}, id: "123456789012", alias: "acme-prod", aliasUnreadable: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.protected.MatchAccount(tt.id, tt.alias, tt.aliasUnreadable); got != tt.want {
+				t.Errorf("MatchAccount(%q, %q, %v) = %v, want %v", tt.id, tt.alias, tt.aliasUnreadable, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtectedMatchProfileAndCluster(t *testing.T) {
+	protected := Protected{
+		Profiles: []string{"*-prod", "admin"},
+		Clusters: []string{"prod-*", "payments"},
+	}
+
+	tests := []struct {
+		name  string
+		match func(string) bool
+		value string
+		want  bool
+	}{
+		{name: "profile exact", match: protected.MatchProfile, value: "admin", want: true},
+		{name: "profile glob", match: protected.MatchProfile, value: "acme-prod", want: true},
+		{name: "profile glob mismatch", match: protected.MatchProfile, value: "acme-prod-readonly", want: false},
+		{name: "profile other", match: protected.MatchProfile, value: "acme-dev", want: false},
+		{name: "profile empty", match: protected.MatchProfile, value: "", want: false},
+		{name: "cluster exact", match: protected.MatchCluster, value: "payments", want: true},
+		{name: "cluster glob", match: protected.MatchCluster, value: "prod-eu", want: true},
+		{name: "cluster glob mismatch", match: protected.MatchCluster, value: "staging-prod", want: false},
+		{name: "cluster empty", match: protected.MatchCluster, value: "", want: false},
+		{name: "nothing protected", match: (&Protected{}).MatchCluster, value: "prod-eu", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.match(tt.value); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the way command results are printed
+type Format string
+
+const (
+	// Text is the default colorized, human oriented output
+	Text Format = "text"
+	// Table is a plain table without colors, suitable for grep and awk. Like JSON and YAML
+	// it leaves stdout to the results, with messages on stderr.
+	Table Format = "table"
+	// JSON prints results as indented JSON
+	JSON Format = "json"
+	// YAML prints results as YAML
+	YAML Format = "yaml"
+)
+
+// Formats lists the accepted values of --output
+var Formats = []Format{Text, Table, JSON, YAML}
+
+// Parse returns the format named by value
+func Parse(value string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == strings.ToLower(value) {
+			return format, nil
+		}
+	}
+
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return "", fmt.Errorf("invalid output format '%s'. Valid choices: %s", value, strings.Join(names, ", "))
+}
+
+// IsMachine reports whether the format is meant for other programs, in which case
+// prompts are disabled
+func (f Format) IsMachine() bool {
+	return f == JSON || f == YAML
+}
+
+// Write encodes v as JSON or YAML
+func Write(w io.Writer, format Format, v any) error {
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case YAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("output format '%s' cannot encode structured data", format)
+	}
+}
+
+// TableWriter collects rows and prints them as aligned columns
+type TableWriter struct {
+	headers []string
+	rows    [][]string
+}
+
+// NewTable creates a table with the given column headers
+func NewTable(headers ...string) *TableWriter {
+	return &TableWriter{headers: headers}
+}
+
+// Append adds a row. Whitespace inside cells is collapsed so every row stays on one line.
+func (t *TableWriter) Append(cells ...string) {
+	row := make([]string, len(cells))
+	for i, cell := range cells {
+		row[i] = strings.Join(strings.Fields(cell), " ")
+		if row[i] == "" {
+			row[i] = "-"
+		}
+	}
+	t.rows = append(t.rows, row)
+}
+
+// Len returns the number of rows
+func (t *TableWriter) Len() int {
+	return len(t.rows)
+}
+
+// Lines returns the rendered table, the header first and then one line per row, so callers
+// can decorate single rows
+func (t *TableWriter) Lines() []string {
+	var buffer strings.Builder
+	_ = t.Render(&buffer)
+	return strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+}
+
+// Render prints the table
+func (t *TableWriter) Render(w io.Writer) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(t.headers, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
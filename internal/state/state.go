@@ -0,0 +1,174 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxRecentTargets is how many targets are remembered per account
+const maxRecentTargets = 10
+
+// State is what eaws remembers between runs. Unlike the config file it is written by eaws itself.
+type State struct {
+	// Profiles maps working directories to the AWS profile last picked in them
+	Profiles map[string]string `json:"profiles,omitempty"`
+	// Contexts maps eaws config files to the context selected with 'eaws context use'
+	Contexts map[string]string `json:"contexts,omitempty"`
+	// Recents maps AWS account IDs to the targets last connected to in them, most recent first
+	Recents map[string][]Target `json:"recents,omitempty"`
+	// Aliases maps AWS account IDs to their account alias, empty for accounts without one
+	Aliases map[string]string `json:"accountAliases,omitempty"`
+	// DeniedAliases holds the AWS account IDs whose alias can't be read for lack of permission
+	DeniedAliases map[string]bool `json:"deniedAliases,omitempty"`
+}
+
+// Target is a container eaws connected to
+type Target struct {
+	Region    string    `json:"region"`
+	Cluster   string    `json:"cluster"`
+	Service   string    `json:"service"`
+	Task      string    `json:"task,omitempty"`
+	Container string    `json:"container"`
+	At        time.Time `json:"at"`
+}
+
+// sameContainer reports whether t and other name the same container, whatever task runs it
+func (t Target) sameContainer(other Target) bool {
+	return t.Region == other.Region && t.Cluster == other.Cluster && t.Service == other.Service && t.Container == other.Container
+}
+
+// DefaultPath returns the location of the state file
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "eaws", "state.json"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "eaws", "state.json"), nil
+}
+
+// Load reads the state file. A missing file yields an empty state.
+func Load() (*State, error) {
+	statePath, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{}
+
+	content, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", statePath, err)
+	}
+
+	return s, nil
+}
+
+// Save writes the state file
+func (s *State) Save() error {
+	statePath, err := DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(statePath), 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(statePath, content, 0o600)
+}
+
+// LastProfile returns the profile last picked in dir
+func (s *State) LastProfile(dir string) string {
+	return s.Profiles[dir]
+}
+
+// SetLastProfile remembers the profile picked in dir
+func (s *State) SetLastProfile(dir, profile string) {
+	if s.Profiles == nil {
+		s.Profiles = make(map[string]string)
+	}
+	s.Profiles[dir] = profile
+}
+
+// CurrentContext returns the context selected for the config file at configPath
+func (s *State) CurrentContext(configPath string) string {
+	return s.Contexts[configPath]
+}
+
+// SetCurrentContext remembers the context selected for the config file at configPath
+func (s *State) SetCurrentContext(configPath, name string) {
+	if s.Contexts == nil {
+		s.Contexts = make(map[string]string)
+	}
+	s.Contexts[configPath] = name
+}
+
+// RecentTargets returns the targets last connected to in account, most recent first
+func (s *State) RecentTargets(account string) []Target {
+	return s.Recents[account]
+}
+
+// AddRecentTarget puts target at the top of the recent targets of account, replacing an
+// earlier connection to the same container and forgetting the oldest beyond the limit
+func (s *State) AddRecentTarget(account string, target Target) {
+	if s.Recents == nil {
+		s.Recents = make(map[string][]Target)
+	}
+
+	targets := []Target{target}
+	for _, recent := range s.Recents[account] {
+		if !recent.sameContainer(target) && len(targets) < maxRecentTargets {
+			targets = append(targets, recent)
+		}
+	}
+	s.Recents[account] = targets
+}
+
+// AccountAlias returns the remembered alias of account, and whether it was looked up before
+func (s *State) AccountAlias(account string) (string, bool) {
+	alias, ok := s.Aliases[account]
+	return alias, ok
+}
+
+// SetAccountAlias remembers the alias of account, empty when it has none
+func (s *State) SetAccountAlias(account, alias string) {
+	if s.Aliases == nil {
+		s.Aliases = make(map[string]string)
+	}
+	s.Aliases[account] = alias
+	delete(s.DeniedAliases, account)
+}
+
+// AccountAliasDenied reports whether the alias of account couldn't be read for lack of permission
+func (s *State) AccountAliasDenied(account string) bool {
+	return s.DeniedAliases[account]
+}
+
+// SetAccountAliasDenied remembers that the alias of account can't be read for lack of permission
+func (s *State) SetAccountAliasDenied(account string) {
+	if s.DeniedAliases == nil {
+		s.DeniedAliases = make(map[string]bool)
+	}
+	s.DeniedAliases[account] = true
+	delete(s.Aliases, account)
+}
@@ -7,9 +7,12 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
@@ -27,151 +30,238 @@ func (e *AWSAuthError) Unwrap() error {
 	return e.Cause
 }
 
-// LoadAWSConfig loads AWS configuration with optional profile
-func LoadAWSConfig(profile string) (aws.Config, error) {
-	ctx := context.Background()
-
+// LoadAWSConfig loads AWS configuration with optional profile and region, and returns the
+// identity the verified credentials belong to
+func LoadAWSConfig(ctx context.Context, profile, region string) (aws.Config, *CallerIdentity, error) {
 	// Handle profile configuration
+	var opts []func(*config.LoadOptions) error
 	if profile != "" {
-		if err := setAWSProfile(profile); err != nil {
-			return aws.Config{}, err
-		}
-	} else if os.Getenv("AWS_PROFILE") == "" {
-		// No profile specified and no AWS_PROFILE set, try to use assume
-		if err := tryAssumeProfile(); err != nil {
-			PrintWarning("No AWS profile configured, using default credentials")
+		profileOpts, err := profileOptions(ctx, profile)
+		if err != nil {
+			return aws.Config{}, nil, err
 		}
+		opts = append(opts, profileOpts...)
+	} else if os.Getenv("AWS_PROFILE") == "" && os.Getenv("AWS_ACCESS_KEY_ID") == "" {
+		PrintWarning("No AWS profile configured, using default credentials")
+	}
+
+	// Load AWS configuration, an explicit region overriding the profile's
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
 	}
 
-	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(ctx)
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
-		return aws.Config{}, &AWSAuthError{
+		return aws.Config{}, nil, &AWSAuthError{
 			Message: "Failed to load AWS configuration. Please check your AWS credentials and configuration.",
 			Cause:   err,
 		}
 	}
 
+	if cfg.Region == "" {
+		return aws.Config{}, nil, &AWSAuthError{
+			Message: "No AWS region configured. Use --region, set AWS_REGION or add a region to your AWS profile.",
+		}
+	}
+
 	// Verify credentials by making a simple API call
-	if err := verifyAWSCredentials(cfg); err != nil {
-		return aws.Config{}, err
+	identity, err := verifyAWSCredentials(ctx, cfg)
+	if err != nil {
+		return aws.Config{}, nil, err
 	}
 
-	return cfg, nil
+	return cfg, identity, nil
 }
 
-// setAWSProfile sets the AWS profile using assume command or environment variable
-func setAWSProfile(profile string) error {
-	// Check if 'assume' command is available (from granted tool)
-	if _, err := exec.LookPath("assume"); err == nil {
-		return runAssumeCommand(profile)
+// profileOptions returns the options that load credentials for profile. When granted is
+// installed its credentials are captured into the process; otherwise the profile is read
+// from the shared AWS config files.
+func profileOptions(ctx context.Context, profile string) ([]func(*config.LoadOptions) error, error) {
+	var provider aws.CredentialsProvider
+	var region string
+
+	if grantedPath, err := exec.LookPath("granted"); err == nil {
+		provider = grantedCredentialProcess(grantedPath, profile)
+	} else if assumegoPath, err := exec.LookPath("assumego"); err == nil {
+		creds, err := runAssumeCommand(ctx, assumegoPath, profile)
+		if err != nil {
+			return nil, err
+		}
+		provider = credentials.StaticCredentialsProvider{Value: creds.Credentials}
+		region = creds.Region
+	} else {
+		PrintInfo(fmt.Sprintf("Using AWS profile: %s", profile))
+		return []func(*config.LoadOptions) error{config.WithSharedConfigProfile(profile)}, nil
+	}
+
+	cache := aws.NewCredentialsCache(provider)
+	if _, err := cache.Retrieve(ctx); err != nil {
+		return nil, &AWSAuthError{
+			Message: fmt.Sprintf("Failed to assume AWS profile '%s' with granted. Please check your AWS SSO configuration.", profile),
+			Cause:   err,
+		}
+	}
+
+	PrintSuccess(fmt.Sprintf("Successfully assumed AWS profile: %s", profile))
+
+	// Credentials come from granted, but the region and the rest of the settings still live in
+	// the profile, whatever AWS_PROFILE says
+	opts := []func(*config.LoadOptions) error{
+		config.WithSharedConfigProfile(profile),
+		config.WithCredentialsProvider(cache),
 	}
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	return opts, nil
+}
+
+// grantedCredentialProcess returns a provider that gets (and refreshes) credentials from
+// granted's credential-process mode
+func grantedCredentialProcess(grantedPath, profile string) aws.CredentialsProvider {
+	return processcreds.NewProviderCommand(processcreds.NewCommandBuilderFunc(func(ctx context.Context) (*exec.Cmd, error) {
+		cmd := exec.CommandContext(ctx, grantedPath, "credential-process", "--profile", profile)
+		// granted may need to open a browser or ask for MFA
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+		return cmd, nil
+	}))
+}
 
-	// Fallback to setting AWS_PROFILE environment variable
-	os.Setenv("AWS_PROFILE", profile)
-	PrintInfo(fmt.Sprintf("Using AWS profile: %s", profile))
-	return nil
+// assumedCredentials are the credentials printed by assumego
+type assumedCredentials struct {
+	aws.Credentials
+	Region string
 }
 
-// runAssumeCommand runs the assume command with proper error handling
-func runAssumeCommand(profile string) error {
-	cmd := exec.Command("assume", profile)
+// runAssumeCommand runs assumego, the binary behind granted's assume shell wrapper, and
+// parses the credentials it prints for the wrapper to export
+func runAssumeCommand(ctx context.Context, assumegoPath, profile string) (*assumedCredentials, error) {
+	cmd := exec.CommandContext(ctx, assumegoPath, profile)
+	// Tells assumego it runs under the assume wrapper, which makes it print the credentials
+	cmd.Env = append(os.Environ(), "GRANTED_ALIAS_CONFIGURED=true")
+	cmd.Stdin = os.Stdin
 
 	// Capture stderr to get better error messages
-	var stderr strings.Builder
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
 		errorMsg := stderr.String()
 		if strings.Contains(errorMsg, "no such file or directory") {
-			return &AWSAuthError{
+			return nil, &AWSAuthError{
 				Message: fmt.Sprintf("AWS profile '%s' not found or granted tool not properly configured. Please check your AWS SSO configuration.", profile),
 				Cause:   err,
 			}
 		}
-		return &AWSAuthError{
+		return nil, &AWSAuthError{
 			Message: fmt.Sprintf("Failed to assume AWS profile '%s': %s", profile, errorMsg),
 			Cause:   err,
 		}
 	}
 
-	PrintSuccess(fmt.Sprintf("Successfully assumed AWS profile: %s", profile))
-	return nil
-}
-
-// tryAssumeProfile tries to run assume command without parameters
-func tryAssumeProfile() error {
-	if _, err := exec.LookPath("assume"); err != nil {
-		return fmt.Errorf("assume command not found")
-	}
-
-	cmd := exec.Command("assume")
-	var stderr strings.Builder
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run assume command: %v", err)
+	creds, err := parseAssumeOutput(stdout.String())
+	if err != nil {
+		return nil, &AWSAuthError{
+			Message: fmt.Sprintf("Failed to read credentials for AWS profile '%s' from granted: %v", profile, err),
+			Cause:   err,
+		}
 	}
-
-	return nil
+	return creds, nil
 }
 
-// verifyAWSCredentials verifies that AWS credentials are valid
-func verifyAWSCredentials(cfg aws.Config) error {
-	stsClient := sts.NewFromConfig(cfg)
-	ctx := context.Background()
-
-	_, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
-	if err != nil {
-		// Check for common authentication errors
-		errorStr := err.Error()
-
-		if strings.Contains(errorStr, "NoCredentialsError") {
-			return &AWSAuthError{
-				Message: "No AWS credentials found. Please run 'aws configure' or set up AWS SSO with 'aws sso login'.",
-				Cause:   err,
-			}
+// parseAssumeOutput reads the "GrantedAssume <key id> <secret> <token> <profile> <region> <expiration> ..."
+// line printed by assumego, where missing values are written as None
+func parseAssumeOutput(out string) (*assumedCredentials, error) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 7 || fields[0] != "GrantedAssume" {
+			continue
 		}
 
-		if strings.Contains(errorStr, "TokenRefreshRequired") || strings.Contains(errorStr, "ExpiredToken") {
-			return &AWSAuthError{
-				Message: "AWS credentials have expired. Please run 'aws sso login' to refresh your credentials.",
-				Cause:   err,
+		value := func(i int) string {
+			if fields[i] == "None" {
+				return ""
 			}
+			return fields[i]
 		}
 
-		if strings.Contains(errorStr, "UnauthorizedOperation") || strings.Contains(errorStr, "AccessDenied") {
-			return &AWSAuthError{
-				Message: "AWS credentials are valid but insufficient permissions. Please check your IAM permissions.",
-				Cause:   err,
-			}
+		creds := &assumedCredentials{
+			Credentials: aws.Credentials{
+				AccessKeyID:     value(1),
+				SecretAccessKey: value(2),
+				SessionToken:    value(3),
+				Source:          "granted",
+			},
+			Region: value(5),
+		}
+		if expiration, err := time.Parse(time.RFC3339, value(6)); err == nil {
+			creds.CanExpire = true
+			creds.Expires = expiration
 		}
 
-		return &AWSAuthError{
-			Message: "Failed to verify AWS credentials. Please check your AWS configuration.",
-			Cause:   err,
+		if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+			return nil, fmt.Errorf("no credentials in granted output")
 		}
+		return creds, nil
 	}
 
-	return nil
+	return nil, fmt.Errorf("unexpected granted output")
 }
 
-// CheckAWSProfile ensures AWS profile is configured and credentials are valid
-func CheckAWSProfile(profile string) error {
-	_, err := LoadAWSConfig(profile)
+// CredentialEnv returns the environment variables that hand the credentials and region of cfg
+// to child processes such as the AWS CLI
+func CredentialEnv(ctx context.Context, cfg aws.Config) ([]string, error) {
+	creds, err := cfg.Credentials.Retrieve(ctx)
 	if err != nil {
-		// If it's an AWSAuthError, provide detailed help
-		var authErr *AWSAuthError
-		if errors.As(err, &authErr) {
-			PrintError(authErr.Message)
+		return nil, err
+	}
 
-			// Provide context-aware help
-			helper := NewAWSSetupHelper()
-			helper.PrintSetupInstructions(authErr)
+	return []string{
+		"AWS_ACCESS_KEY_ID=" + creds.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY=" + creds.SecretAccessKey,
+		// Always set, so a stale token inherited from the shell isn't paired with these keys
+		"AWS_SESSION_TOKEN=" + creds.SessionToken,
+		"AWS_SECURITY_TOKEN=",
+		"AWS_REGION=" + cfg.Region,
+		"AWS_DEFAULT_REGION=" + cfg.Region,
+		// A profile would take precedence over the credentials in the AWS CLI
+		"AWS_PROFILE=",
+	}, nil
+}
+
+// verifyAWSCredentials verifies that AWS credentials are valid and returns who they belong to
+func verifyAWSCredentials(ctx context.Context, cfg aws.Config) (*CallerIdentity, error) {
+	stsClient := sts.NewFromConfig(cfg)
 
-			return authErr
+	output, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		message := "Failed to verify AWS credentials. Please check your AWS configuration."
+		switch ClassifyAWSError(err) {
+		case CategoryNoCredentials:
+			message = "No AWS credentials found. Log in with 'eaws login --profile <profile-name>', or run 'eaws doctor' to diagnose the setup."
+		case CategoryAuthExpired:
+			message = "AWS credentials have expired. Please run 'eaws login' to refresh your credentials."
+		case CategoryAccessDenied:
+			message = "AWS credentials are valid but insufficient permissions. Please check your IAM permissions."
+		case CategoryThrottled:
+			message = "AWS is throttling requests. Please wait a moment and try again."
 		}
-		return err
+		return nil, &AWSAuthError{Message: message, Cause: err}
+	}
+
+	return newCallerIdentity(output, cfg.Region), nil
+}
+
+// ExplainAWSError prints advice for a failed command, tailored to the category of the AWS
+// error behind it. Errors that don't come from AWS print nothing.
+func ExplainAWSError(err error, profile string) {
+	var authErr *AWSAuthError
+	if ClassifyAWSError(err) == CategoryUnknown && !errors.As(err, &authErr) {
+		return
 	}
-	return nil
+
+	helper := NewAWSSetupHelper(profile)
+	helper.PrintSetupInstructions(err)
 }
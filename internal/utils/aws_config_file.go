@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AWSConfigSection is a [profile ...] or [sso-session ...] section of the shared AWS config file
+type AWSConfigSection struct {
+	Name     string
+	Line     int
+	Settings map[string]string
+}
+
+// Get returns a setting of the section, or an empty string
+func (s *AWSConfigSection) Get(key string) string {
+	return s.Settings[key]
+}
+
+// AWSConfigFile is the parsed shared AWS config file (~/.aws/config)
+type AWSConfigFile struct {
+	Path        string
+	Profiles    []*AWSConfigSection
+	SSOSessions map[string]*AWSConfigSection
+}
+
+// AWSConfigSyntaxError reports a line of the AWS config file that cannot be parsed
+type AWSConfigSyntaxError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e *AWSConfigSyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Message)
+}
+
+// SSOConfig holds the IAM Identity Center settings a profile logs in with
+type SSOConfig struct {
+	// SessionName is the sso-session the profile refers to, empty for legacy profiles
+	SessionName string
+	StartURL    string
+	Region      string
+	Scopes      []string
+}
+
+// CacheKey returns the key the AWS CLI and SDKs hash to name the token cache file
+func (c SSOConfig) CacheKey() string {
+	if c.SessionName != "" {
+		return c.SessionName
+	}
+	return c.StartURL
+}
+
+// AWSConfigFilePath returns the location of the shared AWS config file, honoring AWS_CONFIG_FILE
+func AWSConfigFilePath() string {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".aws", "config")
+}
+
+// LoadAWSConfigFile reads and parses the shared AWS config file
+func LoadAWSConfigFile() (*AWSConfigFile, error) {
+	path := AWSConfigFilePath()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseAWSConfigFile(path, file)
+}
+
+// ParseAWSConfigFile parses the INI format of the shared AWS config file
+func ParseAWSConfigFile(path string, r io.Reader) (*AWSConfigFile, error) {
+	config := &AWSConfigFile{
+		Path:        path,
+		SSOSessions: make(map[string]*AWSConfigSection),
+	}
+
+	var section *AWSConfigSection
+	var lastKey string
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		// Indented lines continue a nested setting such as "s3 =", which eaws doesn't use
+		if raw[0] == ' ' || raw[0] == '\t' {
+			if lastKey == "" {
+				return nil, &AWSConfigSyntaxError{Path: path, Line: lineNumber, Message: "unexpected indented line"}
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, &AWSConfigSyntaxError{Path: path, Line: lineNumber, Message: "section header is missing ']'"}
+			}
+
+			header := strings.Fields(strings.TrimSpace(line[1 : len(line)-1]))
+			section = &AWSConfigSection{Line: lineNumber, Settings: make(map[string]string)}
+			lastKey = ""
+
+			switch {
+			case len(header) == 1 && header[0] == "default":
+				section.Name = "default"
+				config.Profiles = append(config.Profiles, section)
+			case len(header) == 2 && header[0] == "profile":
+				section.Name = header[1]
+				config.Profiles = append(config.Profiles, section)
+			case len(header) == 2 && header[0] == "sso-session":
+				section.Name = header[1]
+				config.SSOSessions[section.Name] = section
+			case len(header) == 2:
+				// Other section types, e.g. [services ...], are kept out of the profiles
+				section.Name = header[1]
+			default:
+				return nil, &AWSConfigSyntaxError{Path: path, Line: lineNumber, Message: fmt.Sprintf("invalid section header %s", line)}
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, &AWSConfigSyntaxError{Path: path, Line: lineNumber, Message: fmt.Sprintf("expected 'key = value', got %q", line)}
+		}
+		if section == nil {
+			return nil, &AWSConfigSyntaxError{Path: path, Line: lineNumber, Message: "setting outside of a section"}
+		}
+
+		lastKey = strings.TrimSpace(key)
+		section.Settings[lastKey] = strings.TrimSpace(value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Profile returns the profile with the given name
+func (f *AWSConfigFile) Profile(name string) (*AWSConfigSection, bool) {
+	for _, profile := range f.Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return nil, false
+}
+
+// SSOConfig returns the IAM Identity Center settings of a profile, following
+// source_profile for role chains. ok is false when the profile doesn't use SSO.
+func (f *AWSConfigFile) SSOConfig(name string) (SSOConfig, bool) {
+	for seen := map[string]bool{}; !seen[name]; {
+		seen[name] = true
+
+		profile, found := f.Profile(name)
+		if !found {
+			return SSOConfig{}, false
+		}
+
+		if sessionName := profile.Get("sso_session"); sessionName != "" {
+			session, found := f.SSOSessions[sessionName]
+			if !found {
+				return SSOConfig{}, false
+			}
+
+			scopes := []string{"sso:account:access"}
+			if value := session.Get("sso_registration_scopes"); value != "" {
+				scopes = nil
+				for _, scope := range strings.Split(value, ",") {
+					scopes = append(scopes, strings.TrimSpace(scope))
+				}
+			}
+
+			return SSOConfig{
+				SessionName: sessionName,
+				StartURL:    session.Get("sso_start_url"),
+				Region:      session.Get("sso_region"),
+				Scopes:      scopes,
+			}, true
+		}
+
+		if startURL := profile.Get("sso_start_url"); startURL != "" {
+			return SSOConfig{StartURL: startURL, Region: profile.Get("sso_region")}, true
+		}
+
+		name = profile.Get("source_profile")
+		if name == "" {
+			return SSOConfig{}, false
+		}
+	}
+
+	return SSOConfig{}, false
+}
+
+// AccountID returns the account a profile signs in to, from its SSO settings or role ARN
+func (s *AWSConfigSection) AccountID() string {
+	for _, key := range []string{"sso_account_id", "granted_sso_account_id"} {
+		if value := s.Get(key); value != "" {
+			return value
+		}
+	}
+	// arn:aws:iam::<account>:role/<name>
+	if parts := strings.Split(s.Get("role_arn"), ":"); len(parts) >= 6 {
+		return parts[4]
+	}
+	return ""
+}
+
+// RoleName returns the role a profile assumes, from its SSO settings or role ARN
+func (s *AWSConfigSection) RoleName() string {
+	for _, key := range []string{"sso_role_name", "granted_sso_role_name"} {
+		if value := s.Get(key); value != "" {
+			return value
+		}
+	}
+	if roleARN := s.Get("role_arn"); roleARN != "" {
+		return roleARN[strings.LastIndex(roleARN, "/")+1:]
+	}
+	return ""
+}
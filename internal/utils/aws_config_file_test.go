@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAWSConfig = `# Organization accounts
+[default]
+region = eu-west-1
+
+[profile dev]
+sso_session = acme
+sso_account_id = 111111111111
+sso_role_name = Developer
+region = eu-west-1
+s3 =
+  max_concurrent_requests = 20
+
+[profile prod]
+sso_session = acme-scoped
+sso_account_id = 222222222222
+sso_role_name = ReadOnly
+
+[profile legacy]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 333333333333
+sso_role_name = Admin
+
+[profile deploy]
+source_profile = dev
+role_arn = arn:aws:iam::444444444444:role/Deployer
+
+[profile chained]
+source_profile = deploy
+role_arn = arn:aws:iam::555555555555:role/Auditor
+
+[profile keys]
+aws_access_key_id = AKIAEXAMPLE
+
+[profile loop-a]
+source_profile = loop-b
+
+[profile loop-b]
+source_profile = loop-a
+
+[profile orphan]
+sso_session = missing
+
+[sso-session acme]
+sso_start_url = https://acme.awsapps.com/start
+sso_region = eu-west-1
+
+[sso-session acme-scoped]
+sso_start_url = https://acme.awsapps.com/start
+sso_region = eu-west-1
+sso_registration_scopes = sso:account:access, codewhisperer:completions
+
+[services local]
+dynamodb =
+  endpoint_url = http://localhost:8000
+`
+
+func TestParseAWSConfigFile(t *testing.T) {
+	config, err := ParseAWSConfigFile("config", strings.NewReader(testAWSConfig))
+	if err != nil {
+		t.Fatalf("ParseAWSConfigFile() error = %v", err)
+	}
+
+	var names []string
+	for _, profile := range config.Profiles {
+		names = append(names, profile.Name)
+	}
+	wantNames := []string{"default", "dev", "prod", "legacy", "deploy", "chained", "keys", "loop-a", "loop-b", "orphan"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("profiles = %v, want %v", names, wantNames)
+	}
+
+	if len(config.SSOSessions) != 2 || config.SSOSessions["acme"] == nil || config.SSOSessions["acme-scoped"] == nil {
+		t.Errorf("sso sessions = %v, want acme and acme-scoped", config.SSOSessions)
+	}
+
+	dev, _ := config.Profile("dev")
+	if dev.Line != 5 || dev.Get("region") != "eu-west-1" || dev.Get("s3") != "" {
+		t.Errorf("dev = line %d, settings %v", dev.Line, dev.Settings)
+	}
+	if _, found := dev.Settings["max_concurrent_requests"]; found {
+		t.Errorf("nested setting leaked into dev: %v", dev.Settings)
+	}
+}
+
+func TestParseAWSConfigFileSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantLine int
+		wantMsg  string
+	}{
+		{
+			name:     "indented line after a header",
+			content:  "[profile dev]\n  region = eu-west-1\n",
+			wantLine: 2,
+			wantMsg:  "unexpected indented line",
+		},
+		{
+			name:     "unterminated header",
+			content:  "[default]\nregion = eu-west-1\n\n[profile dev\n",
+			wantLine: 4,
+			wantMsg:  "section header is missing ']'",
+		},
+		{
+			name:     "header with too many words",
+			content:  "[profile my dev]\n",
+			wantLine: 1,
+			wantMsg:  "invalid section header [profile my dev]",
+		},
+		{
+			name:     "line without a value",
+			content:  "[default]\nregion\n",
+			wantLine: 2,
+			wantMsg:  `expected 'key = value', got "region"`,
+		},
+		{
+			name:     "setting before any section",
+			content:  "; comment\nregion = eu-west-1\n",
+			wantLine: 2,
+			wantMsg:  "setting outside of a section",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAWSConfigFile("config", strings.NewReader(tt.content))
+
+			var syntaxErr *AWSConfigSyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("ParseAWSConfigFile() error = %v, want an AWSConfigSyntaxError", err)
+			}
+			if syntaxErr.Path != "config" || syntaxErr.Line != tt.wantLine || syntaxErr.Message != tt.wantMsg {
+				t.Errorf("ParseAWSConfigFile() error = %v, want config:%d: %s", err, tt.wantLine, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestSSOConfig(t *testing.T) {
+	config, err := ParseAWSConfigFile("config", strings.NewReader(testAWSConfig))
+	if err != nil {
+		t.Fatalf("ParseAWSConfigFile() error = %v", err)
+	}
+
+	acme := SSOConfig{
+		SessionName: "acme",
+		StartURL:    "https://acme.awsapps.com/start",
+		Region:      "eu-west-1",
+		Scopes:      []string{"sso:account:access"},
+	}
+
+	tests := []struct {
+		profile string
+		want    SSOConfig
+		wantOK  bool
+	}{
+		{profile: "dev", want: acme, wantOK: true},
+		{
+			profile: "prod",
+			want: SSOConfig{
+				SessionName: "acme-scoped",
+				StartURL:    "https://acme.awsapps.com/start",
+				Region:      "eu-west-1",
+				Scopes:      []string{"sso:account:access", "codewhisperer:completions"},
+			},
+			wantOK: true,
+		},
+		{
+			profile: "legacy",
+			want:    SSOConfig{StartURL: "https://legacy.awsapps.com/start", Region: "us-east-1"},
+			wantOK:  true,
+		},
+		{profile: "deploy", want: acme, wantOK: true},
+		{profile: "chained", want: acme, wantOK: true},
+		{profile: "default"},
+		{profile: "keys"},
+		{profile: "loop-a"},
+		{profile: "orphan"},
+		{profile: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			got, ok := config.SSOConfig(tt.profile)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SSOConfig(%q) = %+v, %v, want %+v, %v", tt.profile, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
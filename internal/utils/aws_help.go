@@ -1,162 +1,140 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
 )
 
 // AWSSetupHelper provides helpful setup instructions based on the environment
-type AWSSetupHelper struct{}
+type AWSSetupHelper struct {
+	// profile is the AWS profile in use, empty when none was selected
+	profile string
+}
 
-// NewAWSSetupHelper creates a new AWS setup helper
-func NewAWSSetupHelper() *AWSSetupHelper {
-	return &AWSSetupHelper{}
+// NewAWSSetupHelper creates a new AWS setup helper for the given profile
+func NewAWSSetupHelper(profile string) *AWSSetupHelper {
+	return &AWSSetupHelper{profile: profile}
 }
 
-// PrintSetupInstructions prints context-aware setup instructions
+// PrintSetupInstructions prints the instructions that fit the category of err
 func (h *AWSSetupHelper) PrintSetupInstructions(err error) {
-	fmt.Printf("\n%s\n", Bold("AWS Setup Instructions:"))
-
-	// Detect the environment and provide appropriate instructions
-	h.printEnvironmentSpecificInstructions()
-
-	// Print general troubleshooting
-	h.printGeneralTroubleshooting()
-
-	// Print quick fixes
-	h.printQuickFixes()
+	switch ClassifyAWSError(err) {
+	case CategoryAuthExpired:
+		fmt.Fprintf(messages, "\n%s\n", Bold("AWS session expired:"))
+		h.printRenewInstructions()
+	case CategoryNoCredentials:
+		fmt.Fprintf(messages, "\n%s\n", Bold("No AWS credentials found:"))
+		h.printNoCredentialsInstructions()
+	case CategoryAccessDenied:
+		fmt.Fprintf(messages, "\n%s\n", Bold("Missing AWS permission:"))
+		h.printAccessDeniedInstructions(err)
+	case CategoryThrottled:
+		fmt.Fprintf(messages, "\n%s\n", Bold("AWS throttled the request:"))
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Wait a few seconds and run the command again")
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Avoid --all-regions while other automation is busy with the same account")
+	case CategoryNotFound:
+		fmt.Fprintf(messages, "\n%s\n", Bold("AWS resource not found:"))
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Check the name for typos; omit the flag to pick from a list")
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Check the region with --region, or search them all with --all-regions")
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Check the account with 'eaws whoami'")
+	default:
+		fmt.Fprintf(messages, "\n%s\n", Bold("AWS request failed:"))
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Diagnose the setup: eaws doctor")
+	}
 }
 
-// printEnvironmentSpecificInstructions provides environment-specific setup instructions
-func (h *AWSSetupHelper) printEnvironmentSpecificInstructions() {
-	fmt.Printf("\n%s\n", GreenBold("Environment-specific setup:"))
+// printRenewInstructions explains how to renew the credentials of the profile in use
+func (h *AWSSetupHelper) printRenewInstructions() {
+	profile := EffectiveProfile(h.profile)
 
-	// Check if we're in a corporate environment
-	if h.isCorpEnvironment() {
-		fmt.Printf("  %s %s\n", Blue("•"), "Corporate environment detected")
-		fmt.Printf("    %s\n", "• Use AWS SSO: aws sso login --profile <profile-name>")
-		fmt.Printf("    %s\n", "• Use granted: assume <profile-name>")
-		fmt.Printf("    %s\n", "• Contact your AWS administrator for SSO setup")
+	if _, ok := ProfileSSOConfig(profile); ok {
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Log in again: eaws login --profile "+profile)
 		return
 	}
-
-	// Check if granted is available
 	if h.isGrantedAvailable() {
-		fmt.Printf("  %s %s\n", Blue("•"), "Granted is available:")
-		fmt.Printf("    %s\n", "• List profiles: assume")
-		fmt.Printf("    %s\n", "• Use profile: assume <profile-name>")
-		fmt.Printf("    %s\n", "• Or use with eaws: eaws --profile <profile-name> [command]")
-	} else {
-		fmt.Printf("  %s %s\n", Blue("•"), "Standard AWS setup:")
-		fmt.Printf("    %s\n", "• Configure credentials: aws configure")
-		fmt.Printf("    %s\n", "• Use named profile: aws configure --profile <profile-name>")
-		fmt.Printf("    %s\n", "• Set profile: export AWS_PROFILE=<profile-name>")
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Assume the profile again: assume "+profile)
+		return
 	}
+	fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Refresh the credentials of profile '"+profile+"', or unset expired AWS_SESSION_TOKEN variables")
 }
 
-// printGeneralTroubleshooting provides general troubleshooting steps
-func (h *AWSSetupHelper) printGeneralTroubleshooting() {
-	fmt.Printf("\n%s\n", GreenBold("Troubleshooting:"))
-	fmt.Printf("  %s %s\n", Blue("•"), "Check AWS configuration:")
-	fmt.Printf("    %s\n", "• aws sts get-caller-identity")
-	fmt.Printf("    %s\n", "• aws configure list")
-	fmt.Printf("    %s\n", "• echo $AWS_PROFILE")
-
-	fmt.Printf("  %s %s\n", Blue("•"), "Check AWS SSO (if using SSO):")
-	fmt.Printf("    %s\n", "• aws sso login")
-	fmt.Printf("    %s\n", "• aws configure sso")
-
-	fmt.Printf("  %s %s\n", Blue("•"), "Check credentials location:")
-	fmt.Printf("    %s\n", "• ~/.aws/credentials")
-	fmt.Printf("    %s\n", "• ~/.aws/config")
-}
-
-// printQuickFixes provides quick fix suggestions
-func (h *AWSSetupHelper) printQuickFixes() {
-	fmt.Printf("\n%s\n", GreenBold("Quick fixes:"))
-	fmt.Printf("  %s %s\n", Blue("•"), "For immediate access:")
-	fmt.Printf("    %s\n", "• Set temporary credentials:")
-	fmt.Printf("      %s\n", "export AWS_ACCESS_KEY_ID=your_access_key")
-	fmt.Printf("      %s\n", "export AWS_SECRET_ACCESS_KEY=your_secret_key")
-	fmt.Printf("      %s\n", "export AWS_DEFAULT_REGION=us-east-1")
-
-	fmt.Printf("  %s %s\n", Blue("•"), "For development:")
-	fmt.Printf("    %s\n", "• Use LocalStack for local development")
-	fmt.Printf("    %s\n", "• Use AWS CLI profiles for different environments")
-
-	fmt.Printf("  %s %s\n", Blue("•"), "For CI/CD:")
-	fmt.Printf("    %s\n", "• Use IAM roles for EC2/ECS/Lambda")
-	fmt.Printf("    %s\n", "• Use environment variables in CI systems")
-}
-
-// isCorpEnvironment detects if we're in a corporate environment
-func (h *AWSSetupHelper) isCorpEnvironment() bool {
-	// Check for common corporate environment indicators
-	homeDir, _ := os.UserHomeDir()
-
-	// Check for corporate SSO config
-	if _, err := os.Stat(homeDir + "/.aws/sso"); err == nil {
-		return true
-	}
+// printAccessDeniedInstructions names the denied action and who needs it
+func (h *AWSSetupHelper) printAccessDeniedInstructions(err error) {
+	action, resource := DeniedAction(err)
+	if action != "" {
+		target := "the resource"
+		if resource != "" {
+			target = resource
+		}
+		fmt.Fprintf(messages, "  %s Ask your AWS administrator to allow %s on %s\n", Blue("•"), YellowBold(action), target)
 
-	// Check for common corporate domains in environment
-	if hostname, err := os.Hostname(); err == nil {
-		corpDomains := []string{".corp", ".internal", ".company", ".local"}
-		for _, domain := range corpDomains {
-			if strings.Contains(hostname, domain) {
-				return true
+		if features := FeaturesNeeding(action); len(features) > 0 {
+			var descriptions []string
+			for _, feature := range features {
+				descriptions = append(descriptions, feature.Description)
 			}
+			fmt.Fprintf(messages, "  %s Needed by eaws for: %s\n", Blue("•"), strings.Join(descriptions, ", "))
 		}
+
+		if policy, err := json.MarshalIndent(NewActionPolicy(action, resource), "    ", "  "); err == nil {
+			fmt.Fprintf(messages, "  %s Minimal policy:\n    %s\n", Blue("•"), Cyan(string(policy)))
+		}
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Generate the policy for all of eaws with: eaws iam-policy")
+	} else {
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Ask your AWS administrator for the missing permission")
 	}
+	fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Check that you use the intended account and role: eaws whoami")
+}
 
-	return false
+// printNoCredentialsInstructions explains how to get credentials for the profile in use
+func (h *AWSSetupHelper) printNoCredentialsInstructions() {
+	profile := EffectiveProfile(h.profile)
+
+	if _, ok := ProfileSSOConfig(profile); ok {
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Log in: eaws login --profile "+profile)
+	} else if h.isGrantedAvailable() {
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Assume a profile: assume <profile-name>")
+	} else {
+		fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Add an SSO profile to ~/.aws/config and log in: eaws login --profile <profile-name>")
+	}
+	fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Select a profile with --profile, AWS_PROFILE or an eaws context")
+	fmt.Fprintf(messages, "  %s %s\n", Blue("•"), "Diagnose the setup: eaws doctor")
 }
 
 // isGrantedAvailable checks if granted tool is available
 func (h *AWSSetupHelper) isGrantedAvailable() bool {
-	_, err := os.Stat("/usr/local/bin/assume")
-	if err == nil {
-		return true
+	for _, name := range []string{"assume", "granted"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return true
+		}
 	}
-
-	_, err = os.Stat("/opt/homebrew/bin/assume")
-	return err == nil
+	return false
 }
 
-// DetectAWSIssue tries to detect the specific AWS authentication issue
-func DetectAWSIssue() string {
-	// Check if AWS CLI is installed
-	if !isAWSCLIInstalled() {
-		return "AWS CLI is not installed. Please install it first: https://docs.aws.amazon.com/cli/latest/userguide/install-cliv2.html"
-	}
-
+// DetectAWSIssue tries to detect the specific AWS authentication issue of a profile,
+// empty when none was selected
+func DetectAWSIssue(profile string) string {
 	// Check if credentials file exists
 	homeDir, _ := os.UserHomeDir()
 	credentialsPath := homeDir + "/.aws/credentials"
-	configPath := homeDir + "/.aws/config"
 
 	if _, err := os.Stat(credentialsPath); os.IsNotExist(err) {
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
-			return "No AWS configuration found. Run 'aws configure' to set up your credentials."
+		if _, err := os.Stat(AWSConfigFilePath()); os.IsNotExist(err) {
+			return "No AWS configuration found. Add an SSO profile to ~/.aws/config and run 'eaws login --profile <profile-name>'."
 		}
 	}
 
 	// Check environment variables
-	if os.Getenv("AWS_PROFILE") == "" && os.Getenv("AWS_ACCESS_KEY_ID") == "" {
-		return "No AWS profile or access key set. Set AWS_PROFILE or run 'aws configure'."
+	if profile == "" && os.Getenv("AWS_PROFILE") == "" && os.Getenv("AWS_ACCESS_KEY_ID") == "" {
+		return "No AWS profile or access key set. Use --profile, set AWS_PROFILE or select an eaws context."
 	}
 
-	return "AWS credentials may be expired or invalid. Try 'aws sts get-caller-identity' to verify."
-}
-
-// isAWSCLIInstalled checks if AWS CLI is installed
-func isAWSCLIInstalled() bool {
-	_, err := os.Stat("/usr/local/bin/aws")
-	if err == nil {
-		return true
+	if _, ok := ProfileSSOConfig(EffectiveProfile(profile)); ok {
+		return fmt.Sprintf("AWS SSO credentials may be expired or invalid. Run 'eaws login --profile %s'.", EffectiveProfile(profile))
 	}
 
-	_, err = os.Stat("/opt/homebrew/bin/aws")
-	return err == nil
+	return "AWS credentials may be expired or invalid. Run 'eaws whoami' to verify."
 }
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseAssumeOutput(t *testing.T) {
+	expires := time.Date(2026, 10, 16, 18, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		out     string
+		want    *assumedCredentials
+		wantErr string
+	}{
+		{
+			name: "sso role",
+			out:  "GrantedAssume ASIAEXAMPLE secretExample tokenExample prod eu-west-1 2026-10-16T18:04:05Z false https://acme.awsapps.com/start AdministratorAccess eu-west-1 123456789012\n",
+			want: &assumedCredentials{
+				Credentials: aws.Credentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secretExample", SessionToken: "tokenExample", CanExpire: true, Expires: expires},
+				Region:      "eu-west-1",
+			},
+		},
+		{
+			name: "expiration with offset",
+			out:  "GrantedAssume ASIAEXAMPLE secretExample tokenExample prod eu-west-1 2026-10-16T20:04:05+02:00 false None None None None\n",
+			want: &assumedCredentials{
+				Credentials: aws.Credentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secretExample", SessionToken: "tokenExample", CanExpire: true, Expires: expires},
+				Region:      "eu-west-1",
+			},
+		},
+		{
+			name: "long-term keys with None session token and expiration",
+			out:  "GrantedAssume AKIAEXAMPLE secretExample None dev us-east-1 None false None None None None\n",
+			want: &assumedCredentials{
+				Credentials: aws.Credentials{AccessKeyID: "AKIAEXAMPLE", SecretAccessKey: "secretExample"},
+				Region:      "us-east-1",
+			},
+		},
+		{
+			name: "None region",
+			out:  "GrantedAssume ASIAEXAMPLE secretExample tokenExample prod None 2026-10-16T18:04:05Z false None None None None\n",
+			want: &assumedCredentials{
+				Credentials: aws.Credentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secretExample", SessionToken: "tokenExample", CanExpire: true, Expires: expires},
+			},
+		},
+		{
+			name: "unreadable expiration",
+			out:  "GrantedAssume ASIAEXAMPLE secretExample tokenExample prod eu-west-1 tomorrow false None None None None\n",
+			want: &assumedCredentials{
+				Credentials: aws.Credentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secretExample", SessionToken: "tokenExample"},
+				Region:      "eu-west-1",
+			},
+		},
+		{
+			name: "line after other output",
+			out:  "GrantedDesume\nGrantedAssume ASIAEXAMPLE secretExample tokenExample prod eu-west-1 2026-10-16T18:04:05Z false None None None None",
+			want: &assumedCredentials{
+				Credentials: aws.Credentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secretExample", SessionToken: "tokenExample", CanExpire: true, Expires: expires},
+				Region:      "eu-west-1",
+			},
+		},
+		{
+			name:    "None access key",
+			out:     "GrantedAssume None None None prod eu-west-1 None false None None None None\n",
+			wantErr: "no credentials in granted output",
+		},
+		{
+			name:    "truncated line",
+			out:     "GrantedAssume ASIAEXAMPLE secretExample tokenExample\n",
+			wantErr: "unexpected granted output",
+		},
+		{
+			name:    "empty output",
+			out:     "",
+			wantErr: "unexpected granted output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAssumeOutput(tt.out)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseAssumeOutput() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAssumeOutput() error = %v", err)
+			}
+
+			tt.want.Source = "granted"
+			if got.AccessKeyID != tt.want.AccessKeyID ||
+				got.SecretAccessKey != tt.want.SecretAccessKey ||
+				got.SessionToken != tt.want.SessionToken ||
+				got.Source != tt.want.Source ||
+				got.CanExpire != tt.want.CanExpire ||
+				!got.Expires.Equal(tt.want.Expires) ||
+				got.Region != tt.want.Region {
+				t.Errorf("parseAssumeOutput() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/fatih/color"
@@ -18,8 +19,20 @@ var (
 	GreenBold  = color.New(color.FgGreen, color.Bold).SprintFunc()
 	YellowBold = color.New(color.FgYellow, color.Bold).SprintFunc()
 	CyanBold   = color.New(color.FgCyan, color.Bold).SprintFunc()
+	RedBold    = color.New(color.FgRed, color.Bold).SprintFunc()
+	// Alert is for warnings that must not be overlooked, such as acting on production
+	Alert = color.New(color.FgWhite, color.BgRed, color.Bold).SprintFunc()
 )
 
+// messages receives status messages. It is switched to stderr when results are
+// printed for other programs, so stdout only carries the results.
+var messages io.Writer = os.Stdout
+
+// SetMessageOutput changes where status messages are printed
+func SetMessageOutput(w io.Writer) {
+	messages = w
+}
+
 func init() {
 	// Disable color if NO_COLOR environment variable is set
 	if os.Getenv("NO_COLOR") != "" {
@@ -28,17 +41,17 @@ func init() {
 }
 
 func PrintSuccess(message string) {
-	fmt.Printf("%s %s\n", Green("✓"), message)
+	fmt.Fprintf(messages, "%s %s\n", Green("✓"), message)
 }
 
 func PrintError(message string) {
-	fmt.Printf("%s %s\n", Red("✗"), message)
+	fmt.Fprintf(messages, "%s %s\n", Red("✗"), message)
 }
 
 func PrintInfo(message string) {
-	fmt.Printf("%s %s\n", Blue("ℹ"), message)
+	fmt.Fprintf(messages, "%s %s\n", Blue("ℹ"), message)
 }
 
 func PrintWarning(message string) {
-	fmt.Printf("%s %s\n", Yellow("⚠"), message)
+	fmt.Fprintf(messages, "%s %s\n", Yellow("⚠"), message)
 }
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/smithy-go"
+)
+
+// ErrorCategory classifies AWS failures so every command explains them the same way
+type ErrorCategory string
+
+const (
+	// CategoryUnknown is any error that isn't recognized as one of the others
+	CategoryUnknown ErrorCategory = "unknown"
+	// CategoryAuthExpired means the credentials or SSO session expired or were revoked
+	CategoryAuthExpired ErrorCategory = "auth-expired"
+	// CategoryNoCredentials means no credentials could be found or obtained
+	CategoryNoCredentials ErrorCategory = "no-credentials"
+	// CategoryAccessDenied means the credentials lack an IAM permission
+	CategoryAccessDenied ErrorCategory = "access-denied"
+	// CategoryThrottled means AWS rejected the request because of its rate
+	CategoryThrottled ErrorCategory = "throttled"
+	// CategoryNotFound means the requested resource doesn't exist
+	CategoryNotFound ErrorCategory = "not-found"
+)
+
+// errorCodeCategories maps AWS API error codes to their category
+var errorCodeCategories = map[string]ErrorCategory{
+	"ExpiredToken":                CategoryAuthExpired,
+	"ExpiredTokenException":       CategoryAuthExpired,
+	"RequestExpired":              CategoryAuthExpired,
+	"TokenRefreshRequired":        CategoryAuthExpired,
+	"InvalidGrantException":       CategoryAuthExpired,
+	"InvalidClientTokenId":        CategoryAuthExpired,
+	"UnrecognizedClientException": CategoryAuthExpired,
+	"AccessDenied":                CategoryAccessDenied,
+	"AccessDeniedException":       CategoryAccessDenied,
+	"UnauthorizedOperation":       CategoryAccessDenied,
+	"UnauthorizedException":       CategoryAccessDenied,
+	"Throttling":                  CategoryThrottled,
+	"ThrottlingException":         CategoryThrottled,
+	"ThrottledException":          CategoryThrottled,
+	"TooManyRequestsException":    CategoryThrottled,
+	"RequestLimitExceeded":        CategoryThrottled,
+	"ResourceNotFoundException":   CategoryNotFound,
+	"ClusterNotFoundException":    CategoryNotFound,
+	"ServiceNotFoundException":    CategoryNotFound,
+	"PipelineNotFoundException":   CategoryNotFound,
+	"InvalidInstanceId":           CategoryNotFound,
+}
+
+// ClassifyAWSError returns the category of an error returned by the SDK, looking at API error
+// codes and credential provider error types
+func ClassifyAWSError(err error) ErrorCategory {
+	if err == nil {
+		return CategoryUnknown
+	}
+
+	var invalidToken *ssocreds.InvalidTokenError
+	if errors.As(err, &invalidToken) {
+		return CategoryAuthExpired
+	}
+
+	var profileNotExist config.SharedConfigProfileNotExistError
+	var staticEmpty *credentials.StaticCredentialsEmptyError
+	var processErr *processcreds.ProviderError
+	if errors.As(err, &profileNotExist) || errors.As(err, &staticEmpty) || errors.As(err, &processErr) {
+		return CategoryNoCredentials
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		// The SSO portal answers expired access tokens with UnauthorizedException
+		if apiErr.ErrorCode() == "UnauthorizedException" && failedIn(err, "SSO") {
+			return CategoryAuthExpired
+		}
+		if category, ok := errorCodeCategories[apiErr.ErrorCode()]; ok {
+			return category
+		}
+		if strings.HasSuffix(apiErr.ErrorCode(), "NotFoundException") {
+			return CategoryNotFound
+		}
+		return CategoryUnknown
+	}
+
+	// The default credential chain ends with the EC2 instance metadata service, so failing
+	// to reach it means nothing earlier in the chain had credentials
+	if failedIn(err, "ec2imds") {
+		return CategoryNoCredentials
+	}
+
+	return CategoryUnknown
+}
+
+// failedIn reports whether an operation of the given service failed anywhere in the error
+// chain. Credential errors are nested in the operation error of the call that needed them.
+func failedIn(err error, serviceID string) bool {
+	var opErr *smithy.OperationError
+	for errors.As(err, &opErr) {
+		if opErr.ServiceID == serviceID {
+			return true
+		}
+		err = opErr.Unwrap()
+	}
+	return false
+}
+
+// deniedActionPattern matches the action and resource in AccessDenied messages such as
+// "User: arn:... is not authorized to perform: ecs:ListServices on resource: arn:... because ..."
+var deniedActionPattern = regexp.MustCompile(`not authorized to perform:? ([\w-]+:[\w*]+)(?: on resource:? (\S+))?`)
+
+// serviceActionPrefixes maps the SDK service IDs of the services eaws calls to their IAM
+// action prefix
+var serviceActionPrefixes = map[string]string{
+	"CloudWatch Logs": "logs",
+	"CodePipeline":    "codepipeline",
+	"EC2":             "ec2",
+	"ECS":             "ecs",
+	"IAM":             "iam",
+	"SSM":             "ssm",
+	"STS":             "sts",
+}
+
+// DeniedAction returns the IAM action and resource named by an AccessDenied error. When the
+// message doesn't name them, the action is derived from the failed operation and the resource
+// is empty. Both are empty for operations of services eaws doesn't know the IAM prefix of.
+func DeniedAction(err error) (action, resource string) {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if match := deniedActionPattern.FindStringSubmatch(apiErr.ErrorMessage()); match != nil {
+			return match[1], strings.TrimSuffix(match[2], ".")
+		}
+	}
+
+	var opErr *smithy.OperationError
+	if errors.As(err, &opErr) {
+		if prefix, ok := serviceActionPrefixes[opErr.ServiceID]; ok {
+			return prefix + ":" + opErr.OperationName, ""
+		}
+	}
+
+	return "", ""
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/smithy-go"
+)
+
+// operationError wraps err the way the SDK reports a failed call
+func operationError(serviceID, operation string, err error) error {
+	return &smithy.OperationError{ServiceID: serviceID, OperationName: operation, Err: err}
+}
+
+// apiError returns an error response of an AWS API
+func apiError(code, message string) error {
+	return &smithy.GenericAPIError{Code: code, Message: message}
+}
+
+// credentialsError wraps err the way the SDK reports credentials that couldn't be retrieved
+// for a call
+func credentialsError(err error) error {
+	return operationError("ECS", "ListClusters", fmt.Errorf("get identity: get credentials: failed to refresh cached credentials, %w", err))
+}
+
+func TestClassifyAWSError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCategory
+	}{
+		{name: "nil", err: nil, want: CategoryUnknown},
+		{name: "plain error", err: errors.New("boom"), want: CategoryUnknown},
+		{
+			name: "expired token",
+			err:  operationError("STS", "GetCallerIdentity", apiError("ExpiredToken", "The security token included in the request is expired")),
+			want: CategoryAuthExpired,
+		},
+		{
+			name: "invalid SSO token",
+			err:  credentialsError(&ssocreds.InvalidTokenError{Err: errors.New("the SSO session has expired or is invalid")}),
+			want: CategoryAuthExpired,
+		},
+		{
+			name: "SSO portal rejects the access token",
+			err:  credentialsError(operationError("SSO", "GetRoleCredentials", apiError("UnauthorizedException", "Session token not found or invalid"))),
+			want: CategoryAuthExpired,
+		},
+		{
+			name: "unauthorized outside SSO",
+			err:  operationError("AppSync", "ListGraphqlApis", apiError("UnauthorizedException", "You are not authorized")),
+			want: CategoryAccessDenied,
+		},
+		{
+			name: "missing profile",
+			err:  fmt.Errorf("failed to load configuration: %w", config.SharedConfigProfileNotExistError{Profile: "prod"}),
+			want: CategoryNoCredentials,
+		},
+		{
+			name: "empty static credentials",
+			err:  credentialsError(&credentials.StaticCredentialsEmptyError{}),
+			want: CategoryNoCredentials,
+		},
+		{
+			name: "failing credential process",
+			err:  credentialsError(&processcreds.ProviderError{Err: errors.New("exit status 1")}),
+			want: CategoryNoCredentials,
+		},
+		{
+			name: "no credentials anywhere in the chain",
+			err:  credentialsError(operationError("ec2imds", "GetMetadata", errors.New("dial tcp 169.254.169.254:80: connect: host is down"))),
+			want: CategoryNoCredentials,
+		},
+		{
+			name: "access denied",
+			err:  operationError("ECS", "ListServices", apiError("AccessDeniedException", "User: arn:aws:sts::123456789012:assumed-role/ReadOnly/me is not authorized to perform: ecs:ListServices")),
+			want: CategoryAccessDenied,
+		},
+		{
+			name: "EC2 unauthorized operation",
+			err:  operationError("EC2", "DescribeInstances", apiError("UnauthorizedOperation", "You are not authorized to perform this operation.")),
+			want: CategoryAccessDenied,
+		},
+		{
+			name: "throttled",
+			err:  operationError("CloudWatch Logs", "FilterLogEvents", apiError("ThrottlingException", "Rate exceeded")),
+			want: CategoryThrottled,
+		},
+		{
+			name: "cluster not found",
+			err:  operationError("ECS", "DescribeServices", apiError("ClusterNotFoundException", "Cluster not found.")),
+			want: CategoryNotFound,
+		},
+		{
+			name: "other not found exception",
+			err:  operationError("CodePipeline", "GetPipelineExecution", apiError("PipelineExecutionNotFoundException", "not found")),
+			want: CategoryNotFound,
+		},
+		{
+			name: "other API error",
+			err:  operationError("ECS", "UpdateService", apiError("InvalidParameterException", "bad request")),
+			want: CategoryUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyAWSError(tt.err); got != tt.want {
+				t.Errorf("ClassifyAWSError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeniedAction(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantAction   string
+		wantResource string
+	}{
+		{
+			name: "action and resource in the message",
+			err: operationError("ECS", "ListServices", apiError("AccessDeniedException",
+				"User: arn:aws:sts::123456789012:assumed-role/ReadOnly/me is not authorized to perform: ecs:ListServices on resource: arn:aws:ecs:eu-west-1:123456789012:cluster/prod because no identity-based policy allows the ecs:ListServices action")),
+			wantAction:   "ecs:ListServices",
+			wantResource: "arn:aws:ecs:eu-west-1:123456789012:cluster/prod",
+		},
+		{
+			name: "resource at the end of the sentence",
+			err: operationError("SSM", "StartSession", apiError("AccessDeniedException",
+				"User: arn:aws:sts::123456789012:assumed-role/ReadOnly/me is not authorized to perform: ssm:StartSession on resource: arn:aws:ec2:eu-west-1:123456789012:instance/i-0123456789abcdef0.")),
+			wantAction:   "ssm:StartSession",
+			wantResource: "arn:aws:ec2:eu-west-1:123456789012:instance/i-0123456789abcdef0",
+		},
+		{
+			name: "action without resource",
+			err: operationError("IAM", "ListAccountAliases", apiError("AccessDenied",
+				"User: arn:aws:sts::123456789012:assumed-role/ReadOnly/me is not authorized to perform: iam:ListAccountAliases")),
+			wantAction: "iam:ListAccountAliases",
+		},
+		{
+			name:       "action from the failed operation",
+			err:        operationError("ECS", "ExecuteCommand", apiError("AccessDeniedException", "Access denied")),
+			wantAction: "ecs:ExecuteCommand",
+		},
+		{
+			name:       "service with another action prefix",
+			err:        operationError("CloudWatch Logs", "StartQuery", apiError("AccessDeniedException", "Access denied")),
+			wantAction: "logs:StartQuery",
+		},
+		{
+			name: "service without a known action prefix",
+			err:  operationError("Resource Groups Tagging API", "GetResources", apiError("AccessDeniedException", "Access denied")),
+		},
+		{
+			name: "not an AWS error",
+			err:  errors.New("boom"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, resource := DeniedAction(tt.err)
+			if action != tt.wantAction || resource != tt.wantResource {
+				t.Errorf("DeniedAction() = %q, %q, want %q, %q", action, resource, tt.wantAction, tt.wantResource)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Feature is an eaws feature together with the IAM actions it calls
+type Feature struct {
+	// Name is how the feature is selected with 'eaws iam-policy --commands'
+	Name        string
+	Description string
+	Actions     []string
+}
+
+// ecsSelectionActions are needed by every command that picks a cluster, service and task
+var ecsSelectionActions = []string{"ecs:ListClusters", "ecs:DescribeClusters", "ecs:ListServices", "ecs:DescribeServices", "ecs:ListTasks", "ecs:DescribeTasks"}
+
+// Features lists what each eaws feature needs. sts:GetCallerIdentity is left out as it
+// requires no permission.
+var Features = []Feature{
+	{
+		Name:        "connect",
+		Description: "container connect",
+		Actions: append(append([]string{}, ecsSelectionActions...),
+			"ecs:DescribeContainerInstances", "ecs:ExecuteCommand", "ssm:StartSession", "ssm:TerminateSession"),
+	},
+	{
+		Name:        "list",
+		Description: "container list",
+		Actions:     ecsSelectionActions,
+	},
+	{
+		Name:        "restart",
+		Description: "service restart",
+		Actions:     append(append([]string{}, ecsSelectionActions...), "ecs:UpdateService"),
+	},
+	{
+		Name:        "logs",
+		Description: "logs view and logs query",
+		Actions: append(append([]string{}, ecsSelectionActions...),
+			"ecs:DescribeTaskDefinition", "logs:DescribeLogGroups", "logs:GetLogEvents",
+			"logs:StartQuery", "logs:GetQueryResults", "logs:StopQuery"),
+	},
+	{
+		Name:        "pipeline",
+		Description: "pipeline",
+		Actions:     []string{"codepipeline:ListPipelines", "codepipeline:ListPipelineExecutions", "codepipeline:GetPipelineState"},
+	},
+	{
+		Name:        "regions",
+		Description: "--all-regions",
+		Actions:     []string{"ec2:DescribeRegions"},
+	},
+	{
+		Name:        "whoami",
+		Description: "account alias in whoami and the account banner",
+		Actions:     []string{"iam:ListAccountAliases"},
+	},
+	{
+		Name:        "doctor",
+		Description: "doctor",
+		Actions:     []string{"ecs:ListClusters", "ssm:DescribeSessions"},
+	},
+}
+
+// FeatureNames returns the names of all features
+func FeatureNames() []string {
+	names := make([]string, len(Features))
+	for i, feature := range Features {
+		names[i] = feature.Name
+	}
+	return names
+}
+
+// FindFeatures returns the features with the given names, or all of them when names is empty
+func FindFeatures(names []string) ([]Feature, error) {
+	if len(names) == 0 {
+		return Features, nil
+	}
+
+	var features []Feature
+	for _, name := range names {
+		found := false
+		for _, feature := range Features {
+			if feature.Name == strings.TrimSpace(name) {
+				features = append(features, feature)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown command '%s'. Valid choices: %s", name, strings.Join(FeatureNames(), ", "))
+		}
+	}
+	return features, nil
+}
+
+// FeaturesNeeding returns the features that call an IAM action
+func FeaturesNeeding(action string) []Feature {
+	var features []Feature
+	for _, feature := range Features {
+		for _, featureAction := range feature.Actions {
+			if strings.EqualFold(featureAction, action) {
+				features = append(features, feature)
+				break
+			}
+		}
+	}
+	return features
+}
+
+// PolicyDocument is an IAM policy
+type PolicyDocument struct {
+	Version   string            `json:"Version" yaml:"Version"`
+	Statement []PolicyStatement `json:"Statement" yaml:"Statement"`
+}
+
+// PolicyStatement is a statement of an IAM policy
+type PolicyStatement struct {
+	Sid      string   `json:"Sid,omitempty" yaml:"Sid,omitempty"`
+	Effect   string   `json:"Effect" yaml:"Effect"`
+	Action   []string `json:"Action" yaml:"Action"`
+	Resource string   `json:"Resource" yaml:"Resource"`
+}
+
+// NewPolicy returns the least-privilege policy for the given features, with one statement per
+// feature so it's clear what each permission is for
+func NewPolicy(features []Feature) PolicyDocument {
+	policy := PolicyDocument{Version: "2012-10-17"}
+	for _, feature := range features {
+		actions := append([]string{}, feature.Actions...)
+		sort.Strings(actions)
+		policy.Statement = append(policy.Statement, PolicyStatement{
+			Sid:      "Eaws" + strings.ToUpper(feature.Name[:1]) + feature.Name[1:],
+			Effect:   "Allow",
+			Action:   actions,
+			Resource: "*",
+		})
+	}
+	return policy
+}
+
+// NewActionPolicy returns a policy allowing a single action, on resource or on everything
+func NewActionPolicy(action, resource string) PolicyDocument {
+	if resource == "" {
+		resource = "*"
+	}
+	return PolicyDocument{
+		Version: "2012-10-17",
+		Statement: []PolicyStatement{{
+			Effect:   "Allow",
+			Action:   []string{action},
+			Resource: resource,
+		}},
+	}
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// CallerIdentity describes who eaws acts as
+type CallerIdentity struct {
+	AccountID string `json:"accountId" yaml:"accountId"`
+	// AccountAlias is empty when the account has none or it cannot be read
+	AccountAlias string `json:"accountAlias,omitempty" yaml:"accountAlias,omitempty"`
+	ARN          string `json:"arn" yaml:"arn"`
+	UserID       string `json:"userId" yaml:"userId"`
+	// Role is the assumed role, or the IAM user or root for long-term credentials
+	Role   string `json:"role" yaml:"role"`
+	Region string `json:"region" yaml:"region"`
+	// AliasUnreadable is set when the alias couldn't be read, so an empty AccountAlias doesn't
+	// mean the account has none
+	AliasUnreadable bool `json:"-" yaml:"-"`
+}
+
+// newCallerIdentity converts a GetCallerIdentity response
+func newCallerIdentity(output *sts.GetCallerIdentityOutput, region string) *CallerIdentity {
+	identity := &CallerIdentity{
+		AccountID: aws.ToString(output.Account),
+		ARN:       aws.ToString(output.Arn),
+		UserID:    aws.ToString(output.UserId),
+		Region:    region,
+	}
+
+	// arn:aws:sts::<account>:assumed-role/<role>/<session>, arn:aws:iam::<account>:user/<name>
+	// or arn:aws:iam::<account>:root
+	parts := strings.SplitN(identity.ARN, ":", 6)
+	if len(parts) == 6 {
+		resource := strings.Split(parts[5], "/")
+		switch {
+		case resource[0] == "assumed-role" && len(resource) > 1:
+			identity.Role = resource[1]
+		default:
+			identity.Role = parts[5]
+		}
+	}
+
+	return identity
+}
+
+// LookupAccountAlias fills in the account alias. It is left empty when the alias
+// can't be read, typically for a missing iam:ListAccountAliases permission.
+func (i *CallerIdentity) LookupAccountAlias(ctx context.Context, cfg aws.Config) error {
+	if i.AccountAlias != "" {
+		return nil
+	}
+	output, err := iam.NewFromConfig(cfg).ListAccountAliases(ctx, &iam.ListAccountAliasesInput{})
+	if err != nil {
+		return err
+	}
+	if len(output.AccountAliases) > 0 {
+		i.AccountAlias = output.AccountAliases[0]
+	}
+	return nil
+}
+
+// Account returns the account ID followed by its alias, when known
+func (i *CallerIdentity) Account() string {
+	if i.AccountAlias != "" {
+		return fmt.Sprintf("%s (%s)", i.AccountID, i.AccountAlias)
+	}
+	return i.AccountID
+}
+
+// Banner returns a one-line summary of the account, role and region
+func (i *CallerIdentity) Banner() string {
+	return fmt.Sprintf("Account %s  Role %s  Region %s", YellowBold(i.Account()), Cyan(i.Role), Cyan(i.Region))
+}
@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	cptypes "github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+const (
+	// describeClustersBatchSize is the most clusters DescribeClusters accepts per call
+	describeClustersBatchSize = 100
+	// describeServicesBatchSize is the most services DescribeServices accepts per call
+	describeServicesBatchSize = 10
+	// describeTasksBatchSize is the most tasks DescribeTasks accepts per call
+	describeTasksBatchSize = 100
+)
+
+// ListClusterArns returns the ARNs of every ECS cluster, following all result pages
+func ListClusterArns(ctx context.Context, client ecs.ListClustersAPIClient) ([]string, error) {
+	var arns []string
+	paginator := ecs.NewListClustersPaginator(client, &ecs.ListClustersInput{
+		MaxResults: aws.Int32(100),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		arns = append(arns, page.ClusterArns...)
+	}
+	return arns, nil
+}
+
+// ListServiceArns returns the ARNs of every service in a cluster, following all result pages
+func ListServiceArns(ctx context.Context, client ecs.ListServicesAPIClient, cluster string) ([]string, error) {
+	var arns []string
+	paginator := ecs.NewListServicesPaginator(client, &ecs.ListServicesInput{
+		Cluster:    aws.String(cluster),
+		MaxResults: aws.Int32(100),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		arns = append(arns, page.ServiceArns...)
+	}
+	return arns, nil
+}
+
+// ListTaskArns returns the ARNs of every running task of a service, following all result pages
+func ListTaskArns(ctx context.Context, client ecs.ListTasksAPIClient, cluster, service string) ([]string, error) {
+	var arns []string
+	paginator := ecs.NewListTasksPaginator(client, &ecs.ListTasksInput{
+		Cluster:     aws.String(cluster),
+		ServiceName: aws.String(service),
+		MaxResults:  aws.Int32(100),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		arns = append(arns, page.TaskArns...)
+	}
+	return arns, nil
+}
+
+// describeClustersAPIClient is the ECS client method DescribeClusters needs. Unlike the other
+// calls, the SDK defines no interface for it as it has neither paginator nor waiter.
+type describeClustersAPIClient interface {
+	DescribeClusters(ctx context.Context, params *ecs.DescribeClustersInput, optFns ...func(*ecs.Options)) (*ecs.DescribeClustersOutput, error)
+}
+
+// DescribeClusters describes clusters given by name or ARN, in batches of the size the API accepts
+func DescribeClusters(ctx context.Context, client describeClustersAPIClient, clusters []string) ([]ecstypes.Cluster, error) {
+	var described []ecstypes.Cluster
+	for start := 0; start < len(clusters); start += describeClustersBatchSize {
+		output, err := client.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+			Clusters: clusters[start:min(start+describeClustersBatchSize, len(clusters))],
+		})
+		if err != nil {
+			return nil, err
+		}
+		described = append(described, output.Clusters...)
+	}
+	return described, nil
+}
+
+// DescribeServices describes services of a cluster, in batches of the size the API accepts.
+// The API doesn't keep the order of arns, so callers must match services by ARN. Services
+// that couldn't be described, e.g. because they were deleted meanwhile, are returned as
+// failures rather than left out silently.
+func DescribeServices(ctx context.Context, client ecs.DescribeServicesAPIClient, cluster string, arns []string) ([]ecstypes.Service, []ecstypes.Failure, error) {
+	var services []ecstypes.Service
+	var failures []ecstypes.Failure
+	for start := 0; start < len(arns); start += describeServicesBatchSize {
+		output, err := client.DescribeServices(ctx, &ecs.DescribeServicesInput{
+			Cluster:  aws.String(cluster),
+			Services: arns[start:min(start+describeServicesBatchSize, len(arns))],
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		services = append(services, output.Services...)
+		failures = append(failures, output.Failures...)
+	}
+	return services, failures, nil
+}
+
+// DescribeTasks describes tasks of a cluster, in batches of the size the API accepts. Like
+// DescribeServices it doesn't keep the order of arns and returns the failures.
+func DescribeTasks(ctx context.Context, client ecs.DescribeTasksAPIClient, cluster string, arns []string) ([]ecstypes.Task, []ecstypes.Failure, error) {
+	var tasks []ecstypes.Task
+	var failures []ecstypes.Failure
+	for start := 0; start < len(arns); start += describeTasksBatchSize {
+		output, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster),
+			Tasks:   arns[start:min(start+describeTasksBatchSize, len(arns))],
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		tasks = append(tasks, output.Tasks...)
+		failures = append(failures, output.Failures...)
+	}
+	return tasks, failures, nil
+}
+
+// ListPipelines returns every CodePipeline pipeline, following all result pages
+func ListPipelines(ctx context.Context, client codepipeline.ListPipelinesAPIClient) ([]cptypes.PipelineSummary, error) {
+	var pipelines []cptypes.PipelineSummary
+	paginator := codepipeline.NewListPipelinesPaginator(client, &codepipeline.ListPipelinesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, page.Pipelines...)
+	}
+	return pipelines, nil
+}
+
+// ListLogGroupNames returns the names of every CloudWatch log group starting with prefix,
+// following all result pages
+func ListLogGroupNames(ctx context.Context, client cloudwatchlogs.DescribeLogGroupsAPIClient, prefix string) ([]string, error) {
+	input := &cloudwatchlogs.DescribeLogGroupsInput{}
+	if prefix != "" {
+		input.LogGroupNamePrefix = aws.String(prefix)
+	}
+
+	var names []string
+	paginator := cloudwatchlogs.NewDescribeLogGroupsPaginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range page.LogGroups {
+			names = append(names, aws.ToString(group.LogGroupName))
+		}
+	}
+	return names, nil
+}
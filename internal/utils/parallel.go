@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// Parallel calls fn for every item with at most limit calls running at once.
+// Results are returned in the order of items.
+func Parallel[T, R any](ctx context.Context, items []T, limit int, fn func(context.Context, T) (R, error)) []ParallelResult[T, R] {
+	results := make([]ParallelResult[T, R], len(items))
+	slots := make(chan struct{}, max(1, limit))
+
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			value, err := fn(ctx, item)
+			results[i] = ParallelResult[T, R]{Item: item, Value: value, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// ParallelResult holds the outcome of a call made by Parallel for one item
+type ParallelResult[T, R any] struct {
+	Item  T
+	Value R
+	Err   error
+}
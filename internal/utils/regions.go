@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// RegionResult holds the outcome of a call made in one region
+type RegionResult[T any] struct {
+	Region string
+	Value  T
+	Err    error
+}
+
+// ListEnabledRegions returns the regions enabled for the account, sorted by name
+func ListEnabledRegions(ctx context.Context, cfg aws.Config) ([]string, error) {
+	output, err := ec2.NewFromConfig(cfg).DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []string
+	for _, region := range output.Regions {
+		regions = append(regions, aws.ToString(region.RegionName))
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// regionParallelism bounds how many regions InRegions calls at once
+const regionParallelism = 8
+
+// InRegions calls fn concurrently once per region, with cfg pointed at that region.
+// Results are returned in the order of regions.
+func InRegions[T any](ctx context.Context, cfg aws.Config, regions []string, fn func(context.Context, aws.Config) (T, error)) []RegionResult[T] {
+	parallelResults := Parallel(ctx, regions, regionParallelism, func(ctx context.Context, region string) (T, error) {
+		regionCfg := cfg.Copy()
+		regionCfg.Region = region
+		return fn(ctx, regionCfg)
+	})
+
+	results := make([]RegionResult[T], len(parallelResults))
+	for i, result := range parallelResults {
+		results[i] = RegionResult[T]{Region: result.Item, Value: result.Value, Err: result.Err}
+	}
+	return results
+}
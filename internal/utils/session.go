@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+const sessionManagerPlugin = "session-manager-plugin"
+
+// SessionManagerSession holds the fields of a Session Manager session that the plugin needs
+type SessionManagerSession struct {
+	SessionId  string `json:"SessionId"`
+	StreamUrl  string `json:"StreamUrl"`
+	TokenValue string `json:"TokenValue"`
+}
+
+// FindSessionManagerPlugin returns the path of session-manager-plugin, or an error explaining
+// how to install it. Check it before starting a session, which would otherwise be left open.
+func FindSessionManagerPlugin() (string, error) {
+	pluginPath, err := exec.LookPath(sessionManagerPlugin)
+	if err == nil {
+		return pluginPath, nil
+	}
+
+	install := "Install it from https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html"
+	if runtime.GOOS == "darwin" {
+		install = "Install it with 'brew install --cask session-manager-plugin' or from https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html"
+	}
+	return "", fmt.Errorf("%s not found in PATH, it is needed to open sessions in containers. %s", sessionManagerPlugin, install)
+}
+
+// RunSessionManagerPlugin hands an already started session to session-manager-plugin, using
+// the same arguments the AWS CLI passes to it. request is the start request of the session,
+// which the plugin reads the target from. The plugin gets the credentials of cfg, so it
+// doesn't resolve them again.
+func RunSessionManagerPlugin(ctx context.Context, cfg aws.Config, session SessionManagerSession, request any) error {
+	pluginPath, err := FindSessionManagerPlugin()
+	if err != nil {
+		return err
+	}
+
+	sessionJSON, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode session parameters: %w", err)
+	}
+
+	credentialEnv, err := CredentialEnv(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	endpoint, err := ssmEndpoint(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	// The profile argument is left empty since the credentials come from the environment
+	cmd := exec.Command(pluginPath,
+		string(sessionJSON),
+		cfg.Region,
+		"StartSession",
+		"",
+		string(requestJSON),
+		endpoint)
+
+	cmd.Env = append(os.Environ(), credentialEnv...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// The plugin forwards Ctrl+C to the remote shell, so eaws must not exit on it
+	signal.Ignore(os.Interrupt)
+	defer signal.Reset(os.Interrupt)
+
+	return cmd.Run()
+}
+
+// ssmEndpoint resolves the Session Manager endpoint of cfg as the SDK client does, which honors
+// the partition of the region (e.g. amazonaws.com.cn in China) and custom endpoints
+func ssmEndpoint(ctx context.Context, cfg aws.Config) (string, error) {
+	options := ssm.NewFromConfig(cfg).Options()
+	endpoint, err := options.EndpointResolverV2.ResolveEndpoint(ctx, ssm.EndpointParameters{
+		Region:       aws.String(options.Region),
+		UseFIPS:      aws.Bool(options.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled),
+		UseDualStack: aws.Bool(options.EndpointOptions.UseDualStackEndpoint == aws.DualStackEndpointStateEnabled),
+		Endpoint:     options.BaseEndpoint,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the SSM endpoint: %w", err)
+	}
+	return endpoint.URI.String(), nil
+}
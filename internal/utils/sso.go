@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+// deviceCodeGrantType is the OAuth grant used by the device authorization flow
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// SSOToken is a cached IAM Identity Center token, in the format written by the AWS CLI
+type SSOToken struct {
+	StartURL              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+}
+
+// Expiration returns when the access token expires, or the zero time when unknown
+func (t *SSOToken) Expiration() time.Time {
+	expiresAt, _ := time.Parse(time.RFC3339, t.ExpiresAt)
+	return expiresAt
+}
+
+// Valid reports whether the access token has not expired yet
+func (t *SSOToken) Valid() bool {
+	return time.Now().Before(t.Expiration())
+}
+
+// Refreshable reports whether the SDK can renew the token without a new login.
+// Only profiles using an sso-session section refresh their tokens.
+func (t *SSOToken) Refreshable(sso SSOConfig) bool {
+	return sso.SessionName != "" && t.RefreshToken != "" && t.ClientID != "" && t.ClientSecret != ""
+}
+
+// LoadSSOToken reads the cached token of an SSO configuration
+func LoadSSOToken(sso SSOConfig) (*SSOToken, error) {
+	path, err := ssocreds.StandardCachedTokenFilepath(sso.CacheKey())
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &SSOToken{}
+	if err := json.Unmarshal(content, token); err != nil {
+		return nil, fmt.Errorf("failed to parse cached SSO token %s: %w", path, err)
+	}
+	return token, nil
+}
+
+// saveSSOToken writes the token where the AWS CLI and SDKs look for it
+func saveSSOToken(sso SSOConfig, token *SSOToken) error {
+	path, err := ssocreds.StandardCachedTokenFilepath(sso.CacheKey())
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create SSO cache directory: %w", err)
+	}
+
+	content, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0o600)
+}
+
+// ProfileSSOConfig returns the SSO configuration of a profile from the shared AWS config file
+func ProfileSSOConfig(profile string) (SSOConfig, bool) {
+	configFile, err := LoadAWSConfigFile()
+	if err != nil {
+		return SSOConfig{}, false
+	}
+	return configFile.SSOConfig(profile)
+}
+
+// EffectiveProfile returns the profile the SDK uses: the given one, AWS_PROFILE or default
+func EffectiveProfile(profile string) string {
+	if profile != "" {
+		return profile
+	}
+	if envProfile := os.Getenv("AWS_PROFILE"); envProfile != "" {
+		return envProfile
+	}
+	return "default"
+}
+
+// SSOLogin runs the IAM Identity Center device authorization flow for a profile and caches
+// the resulting token in the same place and format as 'aws sso login'
+func SSOLogin(ctx context.Context, profile string) error {
+	sso, ok := ProfileSSOConfig(profile)
+	if !ok {
+		return fmt.Errorf("profile '%s' is not configured for AWS SSO in %s", profile, AWSConfigFilePath())
+	}
+	if sso.StartURL == "" || sso.Region == "" {
+		return fmt.Errorf("profile '%s' is missing sso_start_url or sso_region", profile)
+	}
+
+	client := ssooidc.New(ssooidc.Options{Region: sso.Region})
+
+	registration, err := client.RegisterClient(ctx, &ssooidc.RegisterClientInput{
+		ClientName: aws.String("eaws"),
+		ClientType: aws.String("public"),
+		Scopes:     sso.Scopes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register SSO client: %w", err)
+	}
+
+	authorization, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     registration.ClientId,
+		ClientSecret: registration.ClientSecret,
+		StartUrl:     aws.String(sso.StartURL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start SSO device authorization: %w", err)
+	}
+
+	verificationURL := aws.ToString(authorization.VerificationUriComplete)
+	PrintInfo("Opening the SSO authorization page in your browser. If it doesn't open, visit:")
+	fmt.Fprintf(messages, "\n  %s\n\n", Cyan(verificationURL))
+	PrintInfo(fmt.Sprintf("Then confirm the code: %s", YellowBold(aws.ToString(authorization.UserCode))))
+	openBrowser(verificationURL)
+
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(authorization.ExpiresIn) * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		tokenOutput, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     registration.ClientId,
+			ClientSecret: registration.ClientSecret,
+			DeviceCode:   authorization.DeviceCode,
+			GrantType:    aws.String(deviceCodeGrantType),
+		})
+
+		var pending *types.AuthorizationPendingException
+		var slowDown *types.SlowDownException
+		switch {
+		case errors.As(err, &pending):
+			if time.Now().After(deadline) {
+				return fmt.Errorf("SSO authorization timed out")
+			}
+			continue
+		case errors.As(err, &slowDown):
+			interval += 5 * time.Second
+			continue
+		case err != nil:
+			return fmt.Errorf("SSO authorization failed: %w", err)
+		}
+
+		token := &SSOToken{
+			StartURL:     sso.StartURL,
+			Region:       sso.Region,
+			AccessToken:  aws.ToString(tokenOutput.AccessToken),
+			ExpiresAt:    time.Now().Add(time.Duration(tokenOutput.ExpiresIn) * time.Second).UTC().Format(time.RFC3339),
+			ClientID:     aws.ToString(registration.ClientId),
+			ClientSecret: aws.ToString(registration.ClientSecret),
+			RefreshToken: aws.ToString(tokenOutput.RefreshToken),
+		}
+		if registration.ClientSecretExpiresAt > 0 {
+			token.RegistrationExpiresAt = time.Unix(registration.ClientSecretExpiresAt, 0).UTC().Format(time.RFC3339)
+		}
+
+		if err := saveSSOToken(sso, token); err != nil {
+			return fmt.Errorf("failed to cache SSO token: %w", err)
+		}
+
+		PrintSuccess(fmt.Sprintf("Logged in to %s (valid until %s)", sso.StartURL, token.Expiration().Local().Format(time.Kitchen)))
+		return nil
+	}
+}
+
+// IsSSOLoginRequired reports whether err comes from a missing or expired SSO token of profile
+func IsSSOLoginRequired(profile string, err error) bool {
+	sso, ok := ProfileSSOConfig(EffectiveProfile(profile))
+	if !ok {
+		return false
+	}
+
+	var invalidToken *ssocreds.InvalidTokenError
+	if errors.As(err, &invalidToken) {
+		return true
+	}
+
+	token, tokenErr := LoadSSOToken(sso)
+	return tokenErr != nil || (!token.Valid() && !token.Refreshable(sso))
+}
+
+// openBrowser tries to open url in the default browser, ignoring failures
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	_ = cmd.Start()
+}